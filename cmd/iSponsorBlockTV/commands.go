@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/dashboard"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runCommand runs a subcommand and returns the process exit code
+func runCommand(args []string, configFile, dataDir string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "config" && args[1] == "check":
+		return configCheck(configFile, dataDir)
+	case len(args) >= 2 && args[0] == "config" && args[1] == "import":
+		return configImport(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "config" && args[1] == "show":
+		return configShow(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "config" && args[1] == "convert":
+		return configConvert(args[2:])
+	case len(args) >= 1 && args[0] == "dashboard":
+		return dashboardCommand(args[1:], configFile, dataDir)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %v\n", args)
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  config check                      validate the config file")
+		fmt.Fprintln(os.Stderr, "  config import [--force] <file>    import a Python iSponsorBlockTV config")
+		fmt.Fprintln(os.Stderr, "  config show [--sources]           print the effective config")
+		fmt.Fprintln(os.Stderr, "  config convert <from> <to>        convert between JSON, YAML and TOML")
+		fmt.Fprintln(os.Stderr, "  dashboard [--theme name]          watch and control the running daemon")
+		return 2
+	}
+}
+
+// configCheck validates the config file and prints every problem found
+func configCheck(configFile, dataDir string) int {
+	cfg, err := config.LoadConfig(configFile, dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	problems := config.Validate(cfg)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if problems.HasErrors() {
+		fmt.Printf("%s has errors\n", cfg.ConfigFile)
+		return 1
+	}
+	fmt.Printf("%s is valid\n", cfg.ConfigFile)
+	return 0
+}
+
+// configShow prints the effective config after environment overrides,
+// optionally with the source of every value
+func configShow(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	showSources := fs.Bool("sources", false, "print where each value came from")
+	showSecrets := fs.Bool("show-secrets", false, "print API keys unmasked")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(configFile, dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("# config file: %s\n", cfg.ConfigFile)
+	fmt.Printf("# data dir: %s\n", cfg.DataDir)
+	for _, setting := range cfg.Settings() {
+		value := setting.Value
+		if !*showSecrets && strings.HasSuffix(setting.Path, "apikey") && value != `""` {
+			value = `"********"`
+		}
+		if *showSources {
+			fmt.Printf("%s = %s  # %s\n", setting.Path, value, setting.Source)
+		} else {
+			fmt.Printf("%s = %s\n", setting.Path, value)
+		}
+	}
+	return 0
+}
+
+// configConvert converts a config file to the format given by the extension
+// of the destination
+func configConvert(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: config convert <from> <to>")
+		return 2
+	}
+
+	if err := config.ConvertFile(args[0], args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to convert config: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Converted %s to %s (%s)\n", args[0], args[1], config.FormatFromPath(args[1]))
+	return 0
+}
+
+// configImport converts a Python iSponsorBlockTV config and writes it to the
+// config file, storing lounge tokens in the data directory
+func configImport(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite an existing config file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: config import [--force] <python config.json>")
+		return 2
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+	imported, report, err := config.ImportPython(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Resolve paths the same way as for loading
+	cfg, err := config.NewConfig(configFile, dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	imported.ConfigFile = cfg.ConfigFile
+	imported.DataDir = cfg.DataDir
+
+	if _, err := os.Stat(imported.ConfigFile); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "%s already exists, use --force to overwrite it\n", imported.ConfigFile)
+		return 1
+	}
+
+	for _, note := range report.Unmapped {
+		fmt.Println("not imported:", note)
+	}
+	for _, problem := range config.Validate(imported) {
+		fmt.Println(problem)
+	}
+
+	if err := config.Save(imported); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+		return 1
+	}
+
+	if len(report.LoungeTokens) > 0 {
+		tokens := ytlounge.NewTokenStore(imported.DataPath(ytlounge.TokensFileName))
+		for screenID, token := range report.LoungeTokens {
+			if err := tokens.Set(screenID, token); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to store lounge token: %v\n", err)
+				return 1
+			}
+		}
+	}
+
+	fmt.Printf("Imported %d devices and %d whitelisted channels into %s\n",
+		len(imported.Devices), len(imported.ChannelWhitelist), imported.ConfigFile)
+	return 0
+}
+
+// dashboardCommand attaches a dashboard to the daemon running with the same
+// data directory
+func dashboardCommand(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	themeName := fs.String("theme", "", "color theme: "+strings.Join(styles.ThemeNames(), ", "))
+	ascii := fs.Bool("ascii", false, "draw boxes with ASCII characters")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := styles.Configure(*themeName, *ascii); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// Only the data directory is needed, so a missing config file is fine
+	cfg, err := config.NewConfig(configFile, dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	client, err := status.Dial(cfg.DataPath(status.SocketFileName))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	if _, err := tea.NewProgram(dashboard.New(client), tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Dashboard failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
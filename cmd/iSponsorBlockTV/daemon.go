@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/device"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/dial"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/schedule"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+)
+
+// scheduleInterval is how often the schedules are re-evaluated
+const scheduleInterval = 30 * time.Second
+
+// discoveryInterval is how often the local network is searched for devices
+// besides listening for their announcements
+const discoveryInterval = 2 * time.Minute
+
+// listenerRetryInterval is how long to wait before starting a listener
+// again after it failed to start
+const listenerRetryInterval = 30 * time.Second
+
+// Daemon runs a DeviceListener for every configured device and applies
+// config changes to them without restarting unaffected lounge sessions
+type Daemon struct {
+	mu             sync.Mutex
+	ctx            context.Context
+	cfg            *config.Config
+	listeners      map[string]*runningListener
+	cancelSchedule context.CancelFunc
+	// engine resolves the effective config of devices whose listener is
+	// started later
+	engine *schedule.Engine
+	wg     sync.WaitGroup
+	// network holds every device seen on the local network by screen ID,
+	// marked disconnected once it leaves
+	network         *device.DeviceManager
+	cancelDiscovery context.CancelFunc
+	// log collects the log entries of every listener for the dashboard
+	log *status.Log
+	// tokens keeps the lounge tokens of every device
+	tokens *ytlounge.TokenStore
+}
+
+// runningListener is a DeviceListener with the means to stop it
+type runningListener struct {
+	listener *DeviceListener
+	device   config.DeviceConfig
+	cancel   context.CancelFunc
+}
+
+// NewDaemon creates a new Daemon for the given config. The log entries of
+// the listeners are added to log.
+func NewDaemon(cfg *config.Config, log *status.Log) *Daemon {
+	return &Daemon{
+		cfg:       cfg,
+		listeners: make(map[string]*runningListener),
+		network:   device.NewDeviceManager(),
+		log:       log,
+		tokens:    ytlounge.NewTokenStore(cfg.DataPath(ytlounge.TokensFileName)),
+	}
+}
+
+// Start starts a listener for every device and the schedule engine
+func (d *Daemon) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ctx = ctx
+	engine, err := schedule.NewEngine(d.cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range d.cfg.Devices {
+		d.startListener(device, engine.Current(device).Config)
+	}
+	d.runSchedule(engine)
+	d.runDiscovery()
+
+	return nil
+}
+
+// Reload applies a new config. Listeners of removed devices are stopped,
+// listeners of new devices are started, and the remaining listeners are
+// switched to their new effective config in place. Listeners whose
+// effective config did not change are left alone, and a listener is only
+// restarted when the name it joins the lounge with changed.
+func (d *Daemon) Reload(cfg *config.Config) error {
+	engine, err := schedule.NewEngine(cfg, nil)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	discoveryChanged := !reflect.DeepEqual(d.cfg.Discovery, cfg.Discovery)
+	d.cfg = cfg
+	d.cancelSchedule()
+
+	wanted := make(map[string]bool, len(cfg.Devices))
+	for _, device := range cfg.Devices {
+		wanted[device.ScreenID] = true
+		state := engine.Current(device)
+		effective := state.Config
+		// The listener gets this state below, so the engine only reports
+		// later schedule changes
+		engine.Seed(device, state)
+
+		running, ok := d.listeners[device.ScreenID]
+		switch {
+		case !ok:
+			log.Printf("Config reload: adding device %s", describeDevice(device))
+			d.startListener(device, effective)
+		case running.listener.currentConfig().JoinName != effective.JoinName:
+			log.Printf("Config reload: reconnecting device %s", describeDevice(device))
+			paused := running.listener.skippingPaused()
+			d.stopListener(device.ScreenID)
+			d.startListener(device, effective)
+			if restarted, ok := d.listeners[device.ScreenID]; ok && paused {
+				restarted.listener.SetSkippingPaused(true)
+			}
+		default:
+			running.device = device
+			if len(config.Diff(running.listener.currentConfig(), effective)) > 0 {
+				log.Printf("Config reload: updating device %s", describeDevice(device))
+				running.listener.ApplyConfig(effective)
+			}
+		}
+	}
+
+	for screenID, running := range d.listeners {
+		if !wanted[screenID] {
+			log.Printf("Config reload: removing device %s", describeDevice(running.device))
+			d.stopListener(screenID)
+		}
+	}
+
+	d.runSchedule(engine)
+	if discoveryChanged {
+		log.Println("Config reload: restarting discovery")
+		d.cancelDiscovery()
+		d.runDiscovery()
+	}
+	return nil
+}
+
+// Wait stops every listener and waits for them to exit
+func (d *Daemon) Wait() {
+	d.mu.Lock()
+	if d.cancelSchedule != nil {
+		d.cancelSchedule()
+	}
+	if d.cancelDiscovery != nil {
+		d.cancelDiscovery()
+	}
+	for screenID := range d.listeners {
+		d.stopListener(screenID)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// startListener starts a listener for device. A listener that fails to
+// start is retried after listenerRetryInterval. d.mu must be held.
+func (d *Daemon) startListener(device config.DeviceConfig, effective *config.Config) {
+	apiHelper := api.NewAPIHelper(effective, &http.Client{
+		Timeout: 10 * time.Second,
+	})
+	listener, err := NewDeviceListener(apiHelper, effective, &Device{
+		Name:     device.Name,
+		Offset:   device.Offset,
+		ScreenID: device.ScreenID,
+	}, d.cfg.Debug, &http.Client{
+		Timeout: 10 * time.Second,
+	}, d.tokens)
+	if err != nil {
+		log.Printf("Device %s: %v, retrying in %s", describeDevice(device), err, listenerRetryInterval)
+		time.AfterFunc(listenerRetryInterval, func() { d.retryListener(device.ScreenID) })
+		return
+	}
+	label := device.Name
+	if label == "" {
+		label = device.ScreenID
+	}
+	listener.logger.AddHook(d.log.Hook(label))
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.listeners[device.ScreenID] = &runningListener{
+		listener: listener,
+		device:   device,
+		cancel:   cancel,
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer listener.httpClient.CloseIdleConnections()
+		listener.Loop(ctx)
+	}()
+}
+
+// retryListener starts the listener of a screen ID that failed to start,
+// unless the device was removed or its listener started meanwhile
+func (d *Daemon) retryListener(screenID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx.Err() != nil {
+		return
+	}
+	if _, ok := d.listeners[screenID]; ok {
+		return
+	}
+	for _, device := range d.cfg.Devices {
+		if device.ScreenID == screenID {
+			d.startListener(device, d.engine.Current(device).Config)
+			return
+		}
+	}
+}
+
+// stopListener stops the listener of a screen ID. d.mu must be held.
+func (d *Daemon) stopListener(screenID string) {
+	if running, ok := d.listeners[screenID]; ok {
+		running.cancel()
+		running.listener.Cancel()
+		delete(d.listeners, screenID)
+	}
+}
+
+// Devices returns the state of every device, in config order
+func (d *Daemon) Devices() []status.DeviceStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	devices := make([]status.DeviceStatus, 0, len(d.listeners))
+	for _, device := range d.cfg.Devices {
+		if running, ok := d.listeners[device.ScreenID]; ok {
+			current := running.listener.Status()
+			if seen, ok := d.network.GetDevice(device.ScreenID); ok {
+				current.Network = status.NetworkOffline
+				if seen.IsConnected {
+					current.Network = status.NetworkOnline
+				}
+				current.Address, _ = seen.CustomData["address"].(string)
+			}
+			if d.engine != nil {
+				current.Schedules = d.activeSchedules(d.engine.Current(device))
+			}
+			devices = append(devices, current)
+		}
+	}
+	return devices
+}
+
+// activeSchedules returns the names of the schedules in effect in state,
+// with the profile each applies. d.mu must be held.
+func (d *Daemon) activeSchedules(state schedule.State) []string {
+	profiles := make(map[string]string, len(d.cfg.Schedules))
+	for _, s := range d.cfg.Schedules {
+		profiles[s.Name] = s.Profile
+	}
+
+	names := make([]string, 0, len(state.Active))
+	for _, name := range state.Active {
+		if profile := profiles[name]; profile != "" {
+			name = fmt.Sprintf("%s (%s)", name, profile)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// SetSkippingPaused pauses or resumes skipping on a device
+func (d *Daemon) SetSkippingPaused(screenID string, paused bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	running, ok := d.listeners[screenID]
+	if !ok {
+		return status.ErrUnknownDevice
+	}
+	running.listener.SetSkippingPaused(paused)
+	return nil
+}
+
+// Skip skips the next segment of a device right away
+func (d *Daemon) Skip(screenID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	running, ok := d.listeners[screenID]
+	if !ok {
+		return status.ErrUnknownDevice
+	}
+	return running.listener.ForceSkip()
+}
+
+// runSchedule switches device settings as schedules start and end. d.mu
+// must be held.
+func (d *Daemon) runSchedule(engine *schedule.Engine) {
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.cancelSchedule = cancel
+	d.engine = engine
+
+	go engine.Run(ctx, scheduleInterval, func(device config.DeviceConfig, state schedule.State) {
+		d.scheduleChanged(ctx, device, state)
+	})
+}
+
+// scheduleChanged switches the listener of a device to the state its
+// schedules changed to. Listeners already running with the effective config
+// of state are left alone.
+func (d *Daemon) scheduleChanged(ctx context.Context, device config.DeviceConfig, state schedule.State) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+	if running, ok := d.listeners[device.ScreenID]; ok {
+		if len(config.Diff(running.listener.currentConfig(), state.Config)) > 0 {
+			running.listener.ApplyConfig(state.Config)
+		}
+		running.listener.logger.Infof("Device %s: %s", describeDevice(device), state)
+	}
+}
+
+// runDiscovery watches the local network for the devices coming and going.
+// d.mu must be held.
+func (d *Daemon) runDiscovery() {
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.cancelDiscovery = cancel
+
+	watcher := dial.NewWatcher(&http.Client{Timeout: 5 * time.Second}, api.DiscoveryFilter(d.cfg))
+	go func() {
+		if err := watcher.Run(ctx, discoveryInterval, func(event dial.Event) {
+			d.handleDiscovery(ctx, event)
+		}); err != nil {
+			log.Printf("Network discovery disabled: %v", err)
+		}
+	}()
+}
+
+// handleDiscovery marks a device online or offline as it is seen on the
+// local network and keeps its address up to date. Listeners of devices
+// coming online reconnect right away.
+func (d *Daemon) handleDiscovery(ctx context.Context, event dial.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+	screenID := event.Device.ScreenID
+	if event.Type == dial.EventRemoved {
+		d.network.UpdateDeviceStatus(screenID, false)
+	} else {
+		d.network.RegisterDevice(event.Device.ManagedDevice())
+	}
+
+	running, ok := d.listeners[screenID]
+	if !ok {
+		if event.Type == dial.EventAdded {
+			log.Printf("Discovery: found unconfigured device %s at %s", describeDevice(event.Device.DeviceConfig()), event.Address())
+		}
+		return
+	}
+
+	switch event.Type {
+	case dial.EventAdded:
+		running.listener.logger.Infof("Device is online at %s", event.Address())
+		running.listener.Wake()
+	case dial.EventChanged:
+		// The lounge session goes through YouTube, not the TV, so the new
+		// address registered above is all that changes
+		running.listener.logger.Infof("Device moved from %s to %s", event.PreviousAddress(), event.Address())
+	case dial.EventRemoved:
+		running.listener.logger.Infof("Device is offline")
+	}
+}
+
+// describeDevice returns the name of a device for log messages
+func describeDevice(device config.DeviceConfig) string {
+	if device.Name == "" {
+		return device.ScreenID
+	}
+	return fmt.Sprintf("%s (%s)", device.Name, device.ScreenID)
+}
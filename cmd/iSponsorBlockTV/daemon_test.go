@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/schedule"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
+)
+
+// newTestDaemon returns a daemon with an idle listener for every device of
+// cfg. Lounge tokens are stored up front, so no request leaves the test.
+func newTestDaemon(t *testing.T, cfg *config.Config) *Daemon {
+	t.Helper()
+
+	d := NewDaemon(cfg, status.NewLog())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	d.ctx = ctx
+	d.cancelSchedule = func() {}
+
+	engine, err := schedule.NewEngine(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.engine = engine
+
+	for _, device := range cfg.Devices {
+		if err := d.tokens.Set(device.ScreenID, "token"); err != nil {
+			t.Fatal(err)
+		}
+		effective := engine.Current(device).Config
+		listener, err := NewDeviceListener(api.NewAPIHelper(effective, http.DefaultClient), effective, &Device{
+			Name:     device.Name,
+			Offset:   device.Offset,
+			ScreenID: device.ScreenID,
+		}, false, http.DefaultClient, d.tokens)
+		if err != nil {
+			t.Fatalf("NewDeviceListener: %v", err)
+		}
+		d.listeners[device.ScreenID] = &runningListener{listener: listener, device: device, cancel: func() {}}
+	}
+	t.Cleanup(d.Wait)
+	return d
+}
+
+func TestReloadOnlyUpdatesChangedDevices(t *testing.T) {
+	cfg := &config.Config{
+		JoinName: "test",
+		DataDir:  t.TempDir(),
+		Devices: []config.DeviceConfig{
+			{ScreenID: "a", Name: "Living room"},
+			{ScreenID: "b", Name: "Bedroom"},
+		},
+	}
+	d := newTestDaemon(t, cfg)
+	before := map[string]*config.Config{
+		"a": d.listeners["a"].listener.currentConfig(),
+		"b": d.listeners["b"].listener.currentConfig(),
+	}
+
+	reloaded := *cfg
+	reloaded.Devices = []config.DeviceConfig{cfg.Devices[0], {ScreenID: "b", Name: "Bedroom", Offset: 0.5}}
+	if err := d.Reload(&reloaded); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if d.listeners["a"].listener.currentConfig() != before["a"] {
+		t.Error("the unchanged device was reconfigured")
+	}
+	if d.listeners["b"].listener.currentConfig() == before["b"] || d.listeners["b"].listener.offset() != 0.5 {
+		t.Error("the changed device kept its old config")
+	}
+
+	// The schedule engine reporting an unchanged state leaves it alone too
+	state := d.engine.Current(reloaded.Devices[0])
+	d.scheduleChanged(d.ctx, reloaded.Devices[0], state)
+	if d.listeners["a"].listener.currentConfig() != before["a"] {
+		t.Error("the schedule engine reconfigured the unchanged device")
+	}
+}
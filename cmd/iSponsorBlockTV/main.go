@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -12,13 +15,18 @@ import (
 
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
 	"github.com/sirupsen/logrus"
 )
 
 // DeviceListener handles communication with a YouTube device
 type DeviceListener struct {
-	apiHelper        *api.APIHelper
+	apiHelper *api.APIHelper
+	// configMu guards config, debug and the name and offset of device, which
+	// ApplyConfig replaces while events are handled
+	configMu         sync.RWMutex
 	config           *config.Config
 	device           *Device
 	debug            bool
@@ -27,6 +35,14 @@ type DeviceListener struct {
 	loungeController *ytlounge.YtLoungeApi
 	task             *Task
 	cancelled        bool
+
+	// status is reported to the dashboard; skipNow forces the skip of the
+	// segment waited for
+	statusMu sync.Mutex
+	status   status.DeviceStatus
+	skipNow  chan struct{}
+	// wake cuts the wait before reconnecting short
+	wake chan struct{}
 }
 
 // Device represents a YouTube device configuration
@@ -42,20 +58,26 @@ type Task struct {
 	cancel context.CancelFunc
 }
 
-// NewDeviceListener creates a new DeviceListener instance
-func NewDeviceListener(apiHelper *api.APIHelper, config *config.Config, device *Device, debug bool, httpClient *http.Client) *DeviceListener {
+// NewDeviceListener creates a new DeviceListener instance. The lounge token
+// of the device is kept in tokens. It fails if the lounge client of the
+// device cannot be created.
+func NewDeviceListener(apiHelper *api.APIHelper, config *config.Config, device *Device, debug bool, httpClient *http.Client, tokens *ytlounge.TokenStore) (*DeviceListener, error) {
 	logger := logrus.New()
 	logger.SetOutput(os.Stdout)
 	logger.SetFormatter(&logrus.TextFormatter{
 		TimestampFormat: "2006-01-02 15:04:05",
 		FullTimestamp:   true,
 	})
+	logger.SetLevel(logLevel(debug))
 
-	client, err := ytlounge.NewClient(config)
+	client, err := ytlounge.NewClient(config, tokens)
 	if err != nil {
-		logger.Fatalf("Failed to create client: %v", err)
+		return nil, fmt.Errorf("failed to create lounge client: %w", err)
 	}
 	loungeController := ytlounge.NewYtLoungeApi(client, apiHelper, logger)
+	loungeController.SetMuteAds(config.MuteAds)
+	loungeController.SetSkipAds(config.SkipAds)
+	loungeController.SetAutoPlay(config.AutoPlay)
 
 	return &DeviceListener{
 		apiHelper:        apiHelper,
@@ -65,16 +87,40 @@ func NewDeviceListener(apiHelper *api.APIHelper, config *config.Config, device *
 		httpClient:       httpClient,
 		logger:           logger,
 		loungeController: loungeController,
+		status:           status.DeviceStatus{State: status.StateDisconnected},
+		skipNow:          make(chan struct{}, 1),
+		wake:             make(chan struct{}, 1),
+	}, nil
+}
+
+// ApplyConfig switches the listener to a new effective config, as returned
+// by config.Config.ForDevice
+func (d *DeviceListener) ApplyConfig(cfg *config.Config) {
+	d.configMu.Lock()
+	if len(cfg.Devices) == 1 {
+		d.device.Name = cfg.Devices[0].Name
+		d.device.Offset = cfg.Devices[0].Offset
 	}
+	d.config = cfg
+	d.debug = cfg.Debug
+	d.configMu.Unlock()
+	d.logger.SetLevel(logLevel(cfg.Debug))
+
+	d.apiHelper.SetConfig(cfg)
+	d.loungeController.SetMuteAds(cfg.MuteAds)
+	d.loungeController.SetSkipAds(cfg.SkipAds)
+	d.loungeController.SetAutoPlay(cfg.AutoPlay)
 }
 
 // Loop handles the main device connection and monitoring loop
 func (d *DeviceListener) Loop(ctx context.Context) {
 	for !d.cancelled {
-		// Subscribe to device events
-		if err := d.loungeController.SubscribeMonitored(ctx, d.handleEvent); err != nil && d.debug {
+		// Handle device events until the subscription ends
+		err := d.loungeController.SubscribeMonitored(ctx, d.handleEvent)
+		if err != nil && d.debugging() {
 			d.logger.Errorf("Error subscribing to device: %v", err)
 		}
+		d.setConnected(false)
 
 		// Wait a bit before retrying
 		select {
@@ -82,20 +128,29 @@ func (d *DeviceListener) Loop(ctx context.Context) {
 			return
 		case <-time.After(10 * time.Second):
 			continue
+		case <-d.wake:
+			continue
 		}
 	}
 }
 
 // handleEvent processes events from the YouTube Lounge
 func (d *DeviceListener) handleEvent(eventType string, args []interface{}) {
+	d.setConnected(true)
+	d.trackEvent(eventType, args)
+
 	switch eventType {
 	case "onStateChange":
+		if len(args) == 0 {
+			return
+		}
 		if data, ok := args[0].(map[string]interface{}); ok {
+			videoID, _ := data["videoId"].(string)
 			state := &ytlounge.PlaybackState{
-				VideoID: data["videoId"].(string),
+				VideoID: videoID,
 				State:   ytlounge.StatePlaying,
 			}
-			if currentTime, ok := data["currentTime"].(float64); ok {
+			if currentTime, ok := ytlounge.EventFloat(data["currentTime"]); ok {
 				state.CurrentTime = currentTime
 			}
 			d.HandlePlaybackStateChange(state)
@@ -112,16 +167,16 @@ func (d *DeviceListener) HandlePlaybackStateChange(state *ytlounge.PlaybackState
 	ctx, cancel := context.WithCancel(context.Background())
 	d.task = &Task{ctx: ctx, cancel: cancel}
 
-	go d.processPlaybackState(state, time.Now())
+	go d.processPlaybackState(ctx, state, time.Now())
 }
 
 // processPlaybackState processes the playback state
-func (d *DeviceListener) processPlaybackState(state *ytlounge.PlaybackState, startTime time.Time) {
+func (d *DeviceListener) processPlaybackState(ctx context.Context, state *ytlounge.PlaybackState, startTime time.Time) {
 	segments := []api.Segment{}
 	if state.VideoID != "" {
 		var err error
 		segments, _, err = d.apiHelper.GetSegments(context.Background(), state.VideoID)
-		if err != nil && d.debug {
+		if err != nil && d.debugging() {
 			d.logger.Errorf("Error getting segments: %v", err)
 		}
 	}
@@ -129,13 +184,13 @@ func (d *DeviceListener) processPlaybackState(state *ytlounge.PlaybackState, sta
 	if state.State == ytlounge.StatePlaying {
 		d.logger.Infof("Playing video %s with %d segments", state.VideoID, len(segments))
 		if len(segments) > 0 {
-			d.timeToSegment(segments, state.CurrentTime, startTime)
+			d.timeToSegment(ctx, segments, state.CurrentTime, startTime)
 		}
 	}
 }
 
-// timeToSegment finds the next segment to skip to
-func (d *DeviceListener) timeToSegment(segments []api.Segment, position float64, startTime time.Time) {
+// timeToSegment finds the next segment, waits for it and skips or mutes it
+func (d *DeviceListener) timeToSegment(ctx context.Context, segments []api.Segment, position float64, startTime time.Time) {
 	var nextSegment *api.Segment
 	var startNextSegment float64
 
@@ -154,24 +209,62 @@ func (d *DeviceListener) timeToSegment(segments []api.Segment, position float64,
 		}
 	}
 
-	if nextSegment != nil {
-		timeToNext := (startNextSegment - position - time.Since(startTime).Seconds()) - d.device.Offset
-		d.skip(timeToNext, nextSegment.End, nextSegment.UUIDs)
+	if nextSegment == nil {
+		return
+	}
+
+	timeToNext := (startNextSegment - position - time.Since(startTime).Seconds()) - d.offset()
+	d.setNextSegment(nextSegment, startNextSegment, time.Now().Add(time.Duration(timeToNext*float64(time.Second))))
+	reached, forced := d.waitForSegment(ctx, timeToNext)
+	d.setNextSegment(nil, 0, time.Time{})
+
+	switch {
+	case !reached:
+	case forced:
+		d.logger.Info("Skip forced from the dashboard")
+		d.skip(nextSegment.End, nextSegment.UUIDs)
+	case d.skippingPaused():
+		d.logger.Infof("Skipping is paused, not handling segment at %f", startNextSegment)
+	case nextSegment.Action == constants.ActionMute:
+		d.mute(nextSegment.End-startNextSegment, nextSegment.UUIDs)
+	default:
+		d.skip(nextSegment.End, nextSegment.UUIDs)
 	}
 }
 
 // skip handles segment skipping
-func (d *DeviceListener) skip(timeTo float64, position float64, uuids []string) {
-	time.Sleep(time.Duration(timeTo * float64(time.Second)))
-
+func (d *DeviceListener) skip(position float64, uuids []string) {
 	d.logger.Infof("Skipping segment: seeking to %f", position)
+	if err := d.loungeController.SeekTo(position); err != nil {
+		d.logger.Errorf("Error seeking: %v", err)
+	}
+
+	d.markViewed(uuids)
+}
 
+// mute handles segment muting
+func (d *DeviceListener) mute(duration float64, uuids []string) {
+	d.logger.Infof("Muting segment for %f seconds", duration)
+	if err := d.loungeController.Mute(true, true); err != nil {
+		d.logger.Errorf("Error muting: %v", err)
+	}
+
+	time.Sleep(time.Duration(duration * float64(time.Second)))
+	if err := d.loungeController.Mute(false, true); err != nil {
+		d.logger.Errorf("Error unmuting: %v", err)
+	}
+
+	d.markViewed(uuids)
+}
+
+// markViewed marks the given segments as viewed
+func (d *DeviceListener) markViewed(uuids []string) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 
 	go func() {
 		defer wg.Done()
-		if err := d.apiHelper.MarkViewedSegments(context.Background(), uuids); err != nil && d.debug {
+		if err := d.apiHelper.MarkViewedSegments(context.Background(), uuids); err != nil && d.debugging() {
 			d.logger.Errorf("Error marking segments as viewed: %v", err)
 		}
 	}()
@@ -188,60 +281,103 @@ func (d *DeviceListener) Cancel() {
 }
 
 func main() {
+	configFile := flag.String("config", "", "path to the config file (env "+config.EnvConfigFile+")")
+	dataDir := flag.String("data-dir", "", "directory for persistent state (env "+config.EnvDataDir+")")
+	flag.Parse()
+
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runCommand(args, *configFile, *dataDir))
+	}
+
 	// Load configuration
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadConfig(*configFile, *dataDir)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Create API helper
-	apiHelper := api.NewAPIHelper(cfg, &http.Client{
-		Timeout: 10 * time.Second,
-	})
-
-	// Create device listeners
-	listeners := make([]*DeviceListener, len(cfg.Devices))
-	for i, deviceConfig := range cfg.Devices {
-		device := &Device{
-			Name:     deviceConfig.Name,
-			Offset:   deviceConfig.Offset,
-			ScreenID: deviceConfig.ScreenID,
-		}
-		listeners[i] = NewDeviceListener(apiHelper, cfg, device, cfg.Debug, &http.Client{
-			Timeout: 10 * time.Second,
-		})
+	// Validate configuration
+	problems := config.Validate(cfg)
+	for _, problem := range problems {
+		log.Println(problem)
+	}
+	if problems.HasErrors() {
+		log.Fatalf("Refusing to start with errors in %s", cfg.ConfigFile)
 	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Keep recent log lines for the dashboard
+	statusLog := status.NewLog()
+	log.SetOutput(io.MultiWriter(os.Stderr, statusLog))
+
 	// Start device listeners
-	var wg sync.WaitGroup
-	for _, device := range listeners {
-		wg.Add(1)
-		go func(d *DeviceListener) {
-			defer wg.Done()
-			d.Loop(ctx)
-		}(device)
+	daemon := NewDaemon(cfg, statusLog)
+	if err := daemon.Start(ctx); err != nil {
+		log.Fatalf("Failed to start: %v", err)
+	}
+
+	// Serve the dashboard
+	go func() {
+		if err := status.Serve(ctx, cfg.DataPath(status.SocketFileName), daemon, statusLog); err != nil {
+			log.Printf("Dashboard disabled: %v", err)
+		}
+	}()
+
+	// Reload on SIGHUP and when the config file changes
+	reload := make(chan struct{}, 1)
+	requestReload := func() {
+		select {
+		case reload <- struct{}{}:
+		default:
+		}
 	}
+	go config.Watch(ctx, cfg.ConfigFile, 2*time.Second, requestReload)
 
 	// Handle signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	// Wait for signal
-	<-sigChan
+	for running := true; running; {
+		select {
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				requestReload()
+			} else {
+				running = false
+			}
+		case <-reload:
+			reloadConfig(daemon, cfg.ConfigFile, cfg.DataDir)
+		}
+	}
 	log.Println("Cancelling tasks and exiting...")
 
 	// Cancel context and wait for tasks to complete
 	cancel()
-	for _, device := range listeners {
-		device.Cancel()
+	daemon.Wait()
+}
+
+// reloadConfig loads and validates the config again and applies it to the
+// daemon. An invalid config is reported and the running config is kept.
+func reloadConfig(daemon *Daemon, configFile, dataDir string) {
+	log.Printf("Reloading config from %s", configFile)
+
+	cfg, err := config.LoadConfig(configFile, dataDir)
+	if err != nil {
+		log.Printf("Keeping the running config, failed to load: %v", err)
+		return
+	}
+
+	problems := config.Validate(cfg)
+	for _, problem := range problems {
+		log.Println(problem)
+	}
+	if problems.HasErrors() {
+		log.Printf("Keeping the running config, %s has errors", configFile)
+		return
 	}
-	wg.Wait()
 
-	// Close HTTP client
-	for _, device := range listeners {
-		device.httpClient.CloseIdleConnections()
+	if err := daemon.Reload(cfg); err != nil {
+		log.Printf("Keeping the running config: %v", err)
 	}
 }
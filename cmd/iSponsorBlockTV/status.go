@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+	"github.com/sirupsen/logrus"
+)
+
+// Status returns the state of the listener for the dashboard
+func (d *DeviceListener) Status() status.DeviceStatus {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	current := d.status
+	d.configMu.RLock()
+	current.ScreenID = d.device.ScreenID
+	current.Name = d.device.Name
+	d.configMu.RUnlock()
+	if current.NextSegment != nil {
+		segment := *current.NextSegment
+		current.NextSegment = &segment
+	}
+	return current
+}
+
+// SetSkippingPaused pauses or resumes skipping segments. Ads are still
+// handled while skipping is paused.
+func (d *DeviceListener) SetSkippingPaused(paused bool) {
+	d.statusMu.Lock()
+	d.status.SkippingPaused = paused
+	d.statusMu.Unlock()
+
+	if paused {
+		d.logger.Info("Skipping paused")
+	} else {
+		d.logger.Info("Skipping resumed")
+	}
+}
+
+// ForceSkip skips the next segment right away, even while skipping is paused
+func (d *DeviceListener) ForceSkip() error {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	if d.status.NextSegment == nil {
+		return status.ErrNoSegment
+	}
+	select {
+	case d.skipNow <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Wake reconnects right away if the listener waits to reconnect, e.g. when
+// the device came back on the network
+func (d *DeviceListener) Wake() {
+	d.statusMu.Lock()
+	disconnected := d.status.State == status.StateDisconnected
+	d.statusMu.Unlock()
+	if !disconnected {
+		return
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// currentConfig returns the effective config of the listener
+func (d *DeviceListener) currentConfig() *config.Config {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return d.config
+}
+
+// debugging reports whether debug logging is enabled
+func (d *DeviceListener) debugging() bool {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return d.debug
+}
+
+// logLevel returns the level of listener loggers for the debug option
+func logLevel(debug bool) logrus.Level {
+	if debug {
+		return logrus.DebugLevel
+	}
+	return logrus.InfoLevel
+}
+
+// offset returns the offset of the device in seconds
+func (d *DeviceListener) offset() float64 {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return d.device.Offset
+}
+
+// skippingPaused reports whether skipping is paused
+func (d *DeviceListener) skippingPaused() bool {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	return d.status.SkippingPaused
+}
+
+// setConnected records whether the lounge subscription is up
+func (d *DeviceListener) setConnected(connected bool) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	switch {
+	case !connected:
+		d.status.State = status.StateDisconnected
+		d.status.NextSegment = nil
+		d.status.Ad = status.AdNone
+	case d.status.State == "" || d.status.State == status.StateDisconnected:
+		d.status.State = status.StateIdle
+	}
+}
+
+// setNextSegment records the segment the listener waits for, nil once it
+// was handled
+func (d *DeviceListener) setNextSegment(segment *api.Segment, start float64, at time.Time) {
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	if segment == nil {
+		d.status.NextSegment = nil
+		return
+	}
+	d.status.NextSegment = &status.SegmentStatus{
+		Categories: segment.Categories,
+		Action:     segment.Action,
+		Start:      start,
+		End:        segment.End,
+		At:         at,
+	}
+}
+
+// waitForSegment waits until a segment is reached. It reports whether the
+// segment was reached and whether a skip was forced before it was.
+func (d *DeviceListener) waitForSegment(ctx context.Context, timeTo float64) (reached, forced bool) {
+	timer := time.NewTimer(time.Duration(timeTo * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, false
+	case <-d.skipNow:
+		return true, true
+	case <-timer.C:
+		return true, false
+	}
+}
+
+// trackEvent records the playback and ad state reported by lounge events
+func (d *DeviceListener) trackEvent(eventType string, args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	data, ok := args[0].(map[string]interface{})
+	if !ok {
+		return
+	}
+	cfg := d.currentConfig()
+
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+
+	switch eventType {
+	case "onStateChange", "nowPlaying":
+		if videoID, ok := data["videoId"].(string); ok && videoID != "" {
+			d.status.VideoID = videoID
+		}
+		if position, ok := ytlounge.EventFloat(data["currentTime"]); ok {
+			d.status.Position = position
+			d.status.UpdatedAt = time.Now()
+		}
+		if state, ok := data["state"].(string); ok {
+			d.status.State = playbackState(state)
+			if d.status.State != status.StatePlaying {
+				d.status.NextSegment = nil
+			}
+		}
+
+	case "onAdStateChange", "adPlaying":
+		switch {
+		case data["adState"] == "0":
+			d.status.Ad = status.AdNone
+		case cfg.SkipAds && data["isSkipEnabled"] == "true":
+			d.status.Ad = status.AdSkipped
+		case cfg.MuteAds:
+			d.status.Ad = status.AdMuted
+		default:
+			d.status.Ad = status.AdPlaying
+		}
+	}
+}
+
+// playbackState maps the state codes of onStateChange to status states
+func playbackState(code string) string {
+	switch code {
+	case "1":
+		return status.StatePlaying
+	case "2":
+		return status.StatePaused
+	case "3":
+		return status.StateBuffering
+	default:
+		return status.StateIdle
+	}
+}
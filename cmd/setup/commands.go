@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/setup"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+)
+
+// Exit codes of the setup subcommands
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitRejected = 3 // a TV code or API key was rejected
+	exitNotFound = 4 // a device or channel does not exist
+)
+
+// commandTimeout bounds the network requests of a single command
+const commandTimeout = 15 * time.Second
+
+// commandError is printed instead of a result when a command fails
+type commandError struct {
+	Error string `json:"error"`
+}
+
+// deviceResult describes a device in command output
+type deviceResult struct {
+	ScreenID   string  `json:"screen_id"`
+	Name       string  `json:"name"`
+	Offset     float64 `json:"offset"`
+	Configured bool    `json:"configured"`
+	Added      bool    `json:"added,omitempty"`
+	// Found by discovery only
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	UDN          string `json:"udn,omitempty"`
+	Address      string `json:"address,omitempty"`
+	// Warning reports a lounge token that could not be stored or deleted
+	// after the config was saved
+	Warning string `json:"warning,omitempty"`
+}
+
+// categoriesResult is printed by categories set
+type categoriesResult struct {
+	SkipCategories  []string          `json:"skip_categories"`
+	CategoryActions map[string]string `json:"category_actions,omitempty"`
+}
+
+// channelResult is printed by whitelist add and remove
+type channelResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Added   bool   `json:"added,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// apiKeyResult is printed by apikey set
+type apiKeyResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Saved   bool   `json:"saved"`
+}
+
+// runCommand runs a subcommand and returns the process exit code. Results
+// are printed to stdout as JSON; failures print {"error": "..."}.
+func runCommand(args []string, configFile, dataDir string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "devices" && args[1] == "list":
+		return devicesList(configFile, dataDir)
+	case len(args) >= 2 && args[0] == "devices" && args[1] == "discover":
+		return devicesDiscover(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "devices" && args[1] == "pair":
+		return devicesPair(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "devices" && args[1] == "remove":
+		return devicesRemove(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "categories" && args[1] == "set":
+		return categoriesSet(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "whitelist" && args[1] == "add":
+		return whitelistAdd(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "whitelist" && args[1] == "remove":
+		return whitelistRemove(args[2:], configFile, dataDir)
+	case len(args) >= 2 && args[0] == "apikey" && args[1] == "set":
+		return apiKeySet(args[2:], configFile, dataDir)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %v\n", args)
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  devices list                          list the configured devices")
+		fmt.Fprintln(os.Stderr, "  devices discover [--add]              find TVs on the local network")
+		fmt.Fprintln(os.Stderr, "  devices pair <code>                   add a TV by its TV code")
+		fmt.Fprintln(os.Stderr, "  devices remove <screen_id|name>       remove a device")
+		fmt.Fprintln(os.Stderr, "  categories set [--mute] <ids>         set the categories to skip or mute")
+		fmt.Fprintln(os.Stderr, "  whitelist add <channel_id|@handle>    whitelist a channel")
+		fmt.Fprintln(os.Stderr, "  whitelist remove <channel_id>         remove a whitelisted channel")
+		fmt.Fprintln(os.Stderr, "  apikey set [--force] [key|-]          check and set the YouTube API key")
+		fmt.Fprintln(os.Stderr, "Run without a command to start the setup wizard.")
+		return exitUsage
+	}
+}
+
+// printJSON prints a command result
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+// fail prints an error result and returns the exit code
+func fail(code int, format string, args ...any) int {
+	printJSON(commandError{Error: fmt.Sprintf(format, args...)})
+	return code
+}
+
+// loadConfig loads the config file, starting from the defaults when it does
+// not exist yet
+func loadConfig(configFile, dataDir string) (*config.Config, error) {
+	cfg, err := config.LoadConfig(configFile, dataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg, err = config.NewConfig(configFile, dataDir)
+	}
+	return cfg, err
+}
+
+// newHTTPClient returns the HTTP client used by the commands
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// devicesList prints the configured devices
+func devicesList(configFile, dataDir string) int {
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	devices := make([]deviceResult, 0, len(cfg.Devices))
+	for _, device := range cfg.Devices {
+		devices = append(devices, deviceResult{
+			ScreenID:   device.ScreenID,
+			Name:       device.Name,
+			Offset:     device.Offset,
+			Configured: true,
+		})
+	}
+	printJSON(devices)
+	return exitOK
+}
+
+// devicesDiscover finds TVs over DIAL and optionally adds the new ones
+func devicesDiscover(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("devices discover", flag.ContinueOnError)
+	add := fs.Bool("add", false, "add the TVs found to the config")
+	if err := fs.Parse(args); err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	found, err := setup.DiscoverDevices(ctx, api.NewAPIHelper(cfg, newHTTPClient()))
+	if err != nil {
+		return fail(exitError, "discovery failed: %v", err)
+	}
+
+	devices := make([]deviceResult, 0, len(found))
+	added := 0
+	for _, device := range found {
+		result := deviceResult{
+			ScreenID:     device.ScreenID,
+			Name:         device.Name,
+			Offset:       device.Offset,
+			Configured:   cfg.HasDevice(device.ScreenID),
+			Manufacturer: device.Manufacturer,
+			Model:        device.ModelName,
+			UDN:          device.UDN,
+			Address:      device.Address(),
+		}
+		if *add && cfg.AddDevice(device.DeviceConfig()) {
+			result.Added = true
+			added++
+		}
+		devices = append(devices, result)
+	}
+
+	if added > 0 {
+		if err := config.Save(cfg); err != nil {
+			return fail(exitError, "failed to save config: %v", err)
+		}
+	}
+	printJSON(devices)
+	return exitOK
+}
+
+// devicesPair adds a TV by its TV code
+func devicesPair(args []string, configFile, dataDir string) int {
+	if len(args) != 1 {
+		return fail(exitUsage, "usage: devices pair <code>")
+	}
+	code, err := ytlounge.ParsePairingCode(args[0])
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	screen, err := ytlounge.Pair(ctx, newHTTPClient(), ytlounge.PairingURL, code)
+	if errors.Is(err, ytlounge.ErrPairingCodeRejected) {
+		return fail(exitRejected, "%v", err)
+	}
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+
+	added := setup.AddScreen(cfg, screen)
+	if added {
+		if err := config.Save(cfg); err != nil {
+			return fail(exitError, "failed to save config: %v", err)
+		}
+	}
+
+	// The token is stored once the config is saved, so a failed save leaves
+	// no token behind. The daemon fetches a new one if storing it fails.
+	device, _ := setup.FindDevice(cfg, screen.ScreenID)
+	result := deviceResult{
+		ScreenID:   device.ScreenID,
+		Name:       device.Name,
+		Offset:     device.Offset,
+		Configured: true,
+		Added:      added,
+	}
+	if screen.LoungeToken != "" {
+		if err := setup.StoreLoungeToken(cfg, screen.ScreenID, screen.LoungeToken); err != nil {
+			result.Warning = err.Error()
+		}
+	}
+	printJSON(result)
+	return exitOK
+}
+
+// devicesRemove removes a device by screen ID or name
+func devicesRemove(args []string, configFile, dataDir string) int {
+	if len(args) != 1 {
+		return fail(exitUsage, "usage: devices remove <screen_id|name>")
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	device, ok := setup.FindDevice(cfg, args[0])
+	if !ok {
+		return fail(exitNotFound, "no device %q", args[0])
+	}
+	cfg.RemoveDevice(device.ScreenID)
+	if err := config.Save(cfg); err != nil {
+		return fail(exitError, "failed to save config: %v", err)
+	}
+
+	// As when pairing, the token follows the saved config. The device is
+	// removed either way, so a token left behind is only a warning.
+	result := deviceResult{
+		ScreenID: device.ScreenID,
+		Name:     device.Name,
+		Offset:   device.Offset,
+	}
+	if err := setup.DeleteLoungeToken(cfg, device.ScreenID); err != nil {
+		result.Warning = err.Error()
+	}
+	printJSON(result)
+	return exitOK
+}
+
+// categoriesSet sets the categories to skip, or to mute with --mute. The
+// other categories are ignored.
+func categoriesSet(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("categories set", flag.ContinueOnError)
+	mute := fs.Bool("mute", false, "mute the categories instead of skipping them")
+	if err := fs.Parse(args); err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+	if fs.NArg() != 1 {
+		return fail(exitUsage, "usage: categories set [--mute] <category,...>")
+	}
+
+	ids, err := setup.ParseCategories(fs.Arg(0))
+	if err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+	for _, id := range ids {
+		if !constants.IsSkippable(id) {
+			return fail(exitUsage, "category %s cannot be skipped or muted", id)
+		}
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	action := constants.ActionSkip
+	if *mute {
+		action = constants.ActionMute
+	}
+	actions := make(map[string]string, len(ids))
+	for _, id := range ids {
+		actions[id] = action
+	}
+	setup.SetCategoryActions(cfg, actions)
+
+	if err := config.Save(cfg); err != nil {
+		return fail(exitError, "failed to save config: %v", err)
+	}
+	printJSON(categoriesResult{
+		SkipCategories:  cfg.SkipCategories,
+		CategoryActions: cfg.CategoryActions,
+	})
+	return exitOK
+}
+
+// whitelistAdd whitelists a channel by ID, @handle or name
+func whitelistAdd(args []string, configFile, dataDir string) int {
+	if len(args) != 1 {
+		return fail(exitUsage, "usage: whitelist add <channel_id|@handle>")
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	channel, err := setup.ResolveChannel(ctx, cfg, args[0])
+	if errors.Is(err, setup.ErrChannelNotFound) {
+		return fail(exitNotFound, "%v", err)
+	}
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+
+	added := cfg.AddChannel(channel)
+	if added {
+		if err := config.Save(cfg); err != nil {
+			return fail(exitError, "failed to save config: %v", err)
+		}
+	}
+	printJSON(channelResult{ID: channel.ID, Name: channel.Name, Added: added})
+	return exitOK
+}
+
+// whitelistRemove removes a whitelisted channel
+func whitelistRemove(args []string, configFile, dataDir string) int {
+	if len(args) != 1 {
+		return fail(exitUsage, "usage: whitelist remove <channel_id>")
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	if !cfg.RemoveChannel(args[0]) {
+		return fail(exitNotFound, "channel %s is not whitelisted", args[0])
+	}
+	if err := config.Save(cfg); err != nil {
+		return fail(exitError, "failed to save config: %v", err)
+	}
+	printJSON(channelResult{ID: args[0], Removed: true})
+	return exitOK
+}
+
+// apiKeySet checks an API key and saves it. The key is read from stdin when
+// it is "-" or missing, so it does not show up in the process list. Keys
+// that are not valid are only saved with --force.
+func apiKeySet(args []string, configFile, dataDir string) int {
+	fs := flag.NewFlagSet("apikey set", flag.ContinueOnError)
+	force := fs.Bool("force", false, "save the key even if the check fails")
+	if err := fs.Parse(args); err != nil {
+		return fail(exitUsage, "%v", err)
+	}
+	if fs.NArg() > 1 {
+		return fail(exitUsage, "usage: apikey set [--force] [key|-]")
+	}
+
+	key := fs.Arg(0)
+	if key == "" || key == "-" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fail(exitUsage, "no API key given on stdin")
+		}
+		key = line
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fail(exitUsage, "the API key is empty")
+	}
+
+	cfg, err := loadConfig(configFile, dataDir)
+	if err != nil {
+		return fail(exitError, "failed to load config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	status, message, err := api.CheckAPIKey(ctx, newHTTPClient(), constants.YouTubeAPI, key)
+	if err != nil && !*force {
+		return fail(exitError, "failed to check the API key: %v", err)
+	}
+
+	result := apiKeyResult{Status: status.String(), Message: message}
+	if err != nil {
+		result.Status = "unchecked"
+		result.Message = err.Error()
+	} else if status != api.APIKeyValid {
+		result.Message = setup.APIKeyProblem(status, message)
+		if !*force {
+			printJSON(result)
+			return exitRejected
+		}
+	}
+
+	cfg.APIKey = key
+	if err := config.Save(cfg); err != nil {
+		return fail(exitError, "failed to save config: %v", err)
+	}
+	result.Saved = true
+	printJSON(result)
+	return exitOK
+}
@@ -1,22 +1,40 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/setup"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	configFile := flag.String("config", "", "path to the config file (env "+config.EnvConfigFile+")")
+	dataDir := flag.String("data-dir", "", "directory for persistent state (env "+config.EnvDataDir+")")
+	themeName := flag.String("theme", "", "color theme: "+strings.Join(styles.ThemeNames(), ", ")+" (default dark, monochrome with NO_COLOR)")
+	ascii := flag.Bool("ascii", false, "draw boxes with ASCII characters (default when the locale is not UTF-8)")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args(), *configFile, *dataDir))
+	}
+
 	// Initialize config
-	cfg, err := config.LoadConfig()
+	cfg, err := loadConfig(*configFile, *dataDir)
 	if err != nil {
 		fmt.Println("Error loading config:", err)
 		os.Exit(1)
 	}
 
+	if err := styles.Configure(*themeName, *ascii); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
 	// Create and run the setup program
 	p := tea.NewProgram(setup.InitialModel(cfg))
 	if _, err := p.Run(); err != nil {
@@ -272,7 +272,7 @@ func (a *ApiHelper) GetVideoID(ctx context.Context, title, artist string) (*Vide
 	params.Add("key", a.config.APIKey)
 	params.Add("part", "snippet")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"search", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"/search", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -315,111 +315,3 @@ func (a *ApiHelper) GetVideoID(ctx context.Context, title, artist string) (*Vide
 
 	return nil, fmt.Errorf("video not found")
 }
-
-// SearchChannels searches for YouTube channels
-func (a *ApiHelper) SearchChannels(ctx context.Context, query string) ([]struct {
-	ID              string
-	Title           string
-	SubscriberCount string
-}, error) {
-	params := url.Values{}
-	params.Add("q", query)
-	params.Add("key", a.config.APIKey)
-	params.Add("part", "snippet")
-	params.Add("type", "channel")
-	params.Add("maxResults", "5")
-
-	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"search", nil)
-	if err != nil {
-		return nil, err
-	}
-	req.URL.RawQuery = params.Encode()
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var data struct {
-		Items []struct {
-			Snippet struct {
-				ChannelID   string `json:"channelId"`
-				ChannelName string `json:"channelTitle"`
-			} `json:"snippet"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
-	}
-
-	channels := make([]struct {
-		ID              string
-		Title           string
-		SubscriberCount string
-	}, 0, len(data.Items))
-	for _, item := range data.Items {
-		// Get channel statistics
-		stats, err := a.getChannelStats(ctx, item.Snippet.ChannelID)
-		if err != nil {
-			continue
-		}
-
-		channels = append(channels, struct {
-			ID              string
-			Title           string
-			SubscriberCount string
-		}{
-			ID:              item.Snippet.ChannelID,
-			Title:           item.Snippet.ChannelName,
-			SubscriberCount: stats,
-		})
-	}
-
-	return channels, nil
-}
-
-// getChannelStats gets the subscriber count for a channel
-func (a *ApiHelper) getChannelStats(ctx context.Context, channelID string) (string, error) {
-	params := url.Values{}
-	params.Add("id", channelID)
-	params.Add("key", a.config.APIKey)
-	params.Add("part", "statistics")
-
-	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"channels", nil)
-	if err != nil {
-		return "", err
-	}
-	req.URL.RawQuery = params.Encode()
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var data struct {
-		Items []struct {
-			Statistics struct {
-				HiddenSubscriberCount bool   `json:"hiddenSubscriberCount"`
-				SubscriberCount       string `json:"subscriberCount"`
-			} `json:"statistics"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
-	}
-
-	if len(data.Items) == 0 {
-		return "", fmt.Errorf("channel not found")
-	}
-
-	stats := data.Items[0].Statistics
-	if stats.HiddenSubscriberCount {
-		return "Hidden", nil
-	}
-
-	return stats.SubscriberCount, nil
-}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+)
+
+// apiKeyTestVideo is the video looked up to test an API key
+const apiKeyTestVideo = "dQw4w9WgXcQ"
+
+// APIKeyStatus is the result of checking a YouTube Data API key
+type APIKeyStatus int
+
+const (
+	// APIKeyValid is a key that can be used
+	APIKeyValid APIKeyStatus = iota
+	// APIKeyInvalid is a key that does not exist
+	APIKeyInvalid
+	// APIKeyQuotaExceeded is a valid key whose daily quota is used up
+	APIKeyQuotaExceeded
+	// APIKeyRestricted is a valid key that may not call the YouTube Data
+	// API, because the API is not enabled or the key is restricted to other
+	// APIs, addresses or referrers
+	APIKeyRestricted
+)
+
+// String describes the status
+func (s APIKeyStatus) String() string {
+	switch s {
+	case APIKeyValid:
+		return "valid"
+	case APIKeyInvalid:
+		return "invalid"
+	case APIKeyQuotaExceeded:
+		return "quota exceeded"
+	case APIKeyRestricted:
+		return "restricted"
+	default:
+		return "unknown"
+	}
+}
+
+// quotaReasons are the error reasons of exhausted quotas
+var quotaReasons = []string{"quotaExceeded", "dailyLimitExceeded", "rateLimitExceeded", "RATE_LIMIT_EXCEEDED"}
+
+// CheckAPIKey tests a YouTube Data API key with a single video lookup, which
+// costs one quota unit. baseURL is normally constants.YouTubeAPI. The
+// returned message is the API's explanation for keys that are not valid.
+func CheckAPIKey(ctx context.Context, httpClient *http.Client, baseURL, key string) (APIKeyStatus, string, error) {
+	params := url.Values{}
+	params.Add("part", "id")
+	params.Add("id", apiKeyTestVideo)
+	params.Add("key", key)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/videos", nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("User-Agent", constants.UserAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to check API key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return APIKeyValid, "", nil
+	}
+
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+			Details []struct {
+				Reason string `json:"reason"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, "", fmt.Errorf("failed to check API key: %s", resp.Status)
+	}
+
+	reasons := make([]string, 0, len(response.Error.Errors)+len(response.Error.Details))
+	for _, e := range response.Error.Errors {
+		reasons = append(reasons, e.Reason)
+	}
+	for _, d := range response.Error.Details {
+		reasons = append(reasons, d.Reason)
+	}
+	message := response.Error.Message
+
+	switch {
+	case containsAny(reasons, quotaReasons...):
+		return APIKeyQuotaExceeded, message, nil
+	case containsAny(reasons, "keyInvalid", "API_KEY_INVALID"),
+		resp.StatusCode == http.StatusBadRequest && strings.Contains(message, "API key not valid"):
+		return APIKeyInvalid, message, nil
+	case resp.StatusCode == http.StatusForbidden:
+		return APIKeyRestricted, message, nil
+	}
+	return 0, "", fmt.Errorf("failed to check API key: %s: %s", resp.Status, message)
+}
+
+// containsAny reports whether values contains any of wanted
+func containsAny(values []string, wanted ...string) bool {
+	for _, value := range values {
+		for _, w := range wanted {
+			if value == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -9,7 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
@@ -19,9 +19,20 @@ import (
 
 // Segment represents a sponsor segment
 type Segment struct {
-	Start float64  `json:"start"`
-	End   float64  `json:"end"`
-	UUIDs []string `json:"uuids"`
+	Start      float64  `json:"start"`
+	End        float64  `json:"end"`
+	UUIDs      []string `json:"uuids"`
+	Categories []string `json:"categories"`
+	Action     string   `json:"action"`
+}
+
+// sponsorBlockSegment is a segment as returned by the SponsorBlock API
+type sponsorBlockSegment struct {
+	Segment       []float64 `json:"segment"`
+	UUID          string    `json:"UUID"`
+	Category      string    `json:"category"`
+	Locked        int       `json:"locked"`
+	VideoDuration float64   `json:"videoDuration"`
 }
 
 // APIHelper handles all API calls and caching
@@ -30,45 +41,93 @@ type APIHelper struct {
 	httpClient       *http.Client
 	cache            *Cache
 	channelWhitelist []string
+	mu               sync.RWMutex
 }
 
 // NewAPIHelper creates a new API helper
 func NewAPIHelper(cfg *config.Config, httpClient *http.Client) *APIHelper {
 	return &APIHelper{
-		cfg:        cfg,
-		httpClient: httpClient,
-		cache:      NewCache(100, 5*time.Minute),
+		cfg:              cfg,
+		httpClient:       httpClient,
+		cache:            NewCache(100, 5*time.Minute),
+		channelWhitelist: channelWhitelistIDs(cfg),
+	}
+}
+
+// SetConfig replaces the config used for subsequent requests
+func (a *APIHelper) SetConfig(cfg *config.Config) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cfg = cfg
+	a.channelWhitelist = channelWhitelistIDs(cfg)
+}
+
+// settings returns the current config and channel whitelist
+func (a *APIHelper) settings() (*config.Config, []string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg, a.channelWhitelist
+}
+
+// channelWhitelistIDs returns the non-empty channel IDs of the whitelist
+func channelWhitelistIDs(cfg *config.Config) []string {
+	ids := make([]string, 0, len(cfg.ChannelWhitelist))
+	for _, channel := range cfg.ChannelWhitelist {
+		if channel.ID != "" {
+			ids = append(ids, channel.ID)
+		}
 	}
+	return ids
 }
 
-// GetSegments retrieves sponsor segments for a video
+// GetSegments retrieves sponsor segments for a video. Each segment is tagged
+// with the action resolved for its category, taking channel rules into account.
 func (a *APIHelper) GetSegments(ctx context.Context, videoID string) ([]Segment, bool, error) {
-	// Check if channel is whitelisted
-	if a.cfg.YouTube.APIKey != "" && len(a.channelWhitelist) > 0 {
-		channelID, err := a.getChannelID(ctx, videoID)
+	cfg, channelWhitelist := a.settings()
+
+	var channelID string
+	if cfg.APIKey != "" && (len(channelWhitelist) > 0 || len(cfg.ChannelRules) > 0) {
+		var err error
+		channelID, err = a.getChannelID(ctx, videoID)
 		if err != nil {
 			return nil, false, err
 		}
 
-		for _, whitelistedID := range a.channelWhitelist {
+		// Check if channel is whitelisted
+		for _, whitelistedID := range channelWhitelist {
 			if whitelistedID == channelID {
 				return []Segment{}, true, nil
 			}
 		}
 	}
 
+	actions := categoryActions(cfg, channelID)
+	categories := make([]string, 0, len(actions))
+	for category, action := range actions {
+		if action != constants.ActionIgnore {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		return []Segment{}, true, nil
+	}
+	sort.Strings(categories)
+
 	// Hash video ID
 	hash := sha256.Sum256([]byte(videoID))
 	videoIDHashed := hex.EncodeToString(hash[:])[:4]
 
 	// Build request
 	params := url.Values{}
-	params.Add("category", strings.Join(a.cfg.SponsorBlock.Categories, ","))
+	for _, category := range categories {
+		params.Add("category", category)
+	}
 	params.Add("actionType", constants.SponsorBlockActionType)
 	params.Add("service", constants.SponsorBlockService)
 
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		fmt.Sprintf("%sskipSegments/%s", constants.SponsorBlockAPI, videoIDHashed), nil)
+		fmt.Sprintf("%s/skipSegments/%s", constants.SponsorBlockAPI, videoIDHashed), nil)
 	if err != nil {
 		return nil, false, err
 	}
@@ -110,11 +169,32 @@ func (a *APIHelper) GetSegments(ctx context.Context, videoID string) ([]Segment,
 		return []Segment{}, true, nil
 	}
 
-	return a.processSegments(segmentsData)
+	return a.processSegments(cfg, segmentsData, actions)
 }
 
-// processSegments processes the segments data
-func (a *APIHelper) processSegments(data map[string]interface{}) ([]Segment, bool, error) {
+// categoryActions returns the category to action map for a channel. The
+// skipped categories are overridden by the global category actions, and
+// channel rules are layered over both.
+func categoryActions(cfg *config.Config, channelID string) map[string]string {
+	actions := make(map[string]string, len(cfg.SkipCategories)+len(cfg.CategoryActions))
+	for _, category := range cfg.SkipCategories {
+		actions[category] = constants.ActionSkip
+	}
+	for category, action := range cfg.CategoryActions {
+		actions[category] = action
+	}
+
+	if rule, ok := cfg.ChannelRules[channelID]; ok && channelID != "" {
+		actions = rule.Apply(actions)
+	}
+
+	return actions
+}
+
+// processSegments processes the segments data, dropping segments whose
+// category is ignored, merging the rest per action and then dropping merged
+// segments that fall outside the segment options of their categories
+func (a *APIHelper) processSegments(cfg *config.Config, data map[string]interface{}, actions map[string]string) ([]Segment, bool, error) {
 	segments := make([]Segment, 0)
 	ignoreTTL := true
 
@@ -123,18 +203,82 @@ func (a *APIHelper) processSegments(data map[string]interface{}) ([]Segment, boo
 		return segments, ignoreTTL, nil
 	}
 
-	// Convert to typed segments
-	typedSegments := make([]struct {
-		Segment []float64 `json:"segment"`
-		UUID    string    `json:"UUID"`
-		Locked  int       `json:"locked"`
-	}, len(rawSegments))
-
-	for i, s := range rawSegments {
+	// Convert to typed segments, grouped by action
+	byAction := make(map[string][]sponsorBlockSegment)
+	videoDuration := 0.0
+	for _, s := range rawSegments {
+		var typed sponsorBlockSegment
 		segmentData, _ := json.Marshal(s)
-		json.Unmarshal(segmentData, &typedSegments[i])
+		if err := json.Unmarshal(segmentData, &typed); err != nil || len(typed.Segment) < 2 {
+			continue
+		}
+
+		ignoreTTL = ignoreTTL && typed.Locked == 1
+		videoDuration = max(videoDuration, typed.VideoDuration)
+
+		action, ok := actions[typed.Category]
+		if !ok || action == constants.ActionIgnore {
+			continue
+		}
+		byAction[action] = append(byAction[action], typed)
 	}
 
+	for action, typedSegments := range byAction {
+		for _, segment := range mergeSegments(typedSegments, action) {
+			options := mergedSegmentOptions(cfg, segment.Categories)
+			if applySegmentOptions(&segment, options, videoDuration) {
+				segments = append(segments, segment)
+			}
+		}
+	}
+
+	// Sort by start time
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].Start < segments[j].Start
+	})
+
+	return segments, ignoreTTL, nil
+}
+
+// mergedSegmentOptions returns the least restrictive segment options of the
+// given categories, so a merged segment is kept whenever one of its
+// categories would keep it
+func mergedSegmentOptions(cfg *config.Config, categories []string) config.SegmentOptions {
+	var merged config.SegmentOptions
+	for i, category := range categories {
+		options := cfg.SegmentOptionsFor(category)
+		if i == 0 {
+			merged = options
+			continue
+		}
+		merged.MinDuration = min(merged.MinDuration, options.MinDuration)
+		merged.StartPadding = min(merged.StartPadding, options.StartPadding)
+		merged.EndPadding = min(merged.EndPadding, options.EndPadding)
+		merged.MinRemaining = min(merged.MinRemaining, options.MinRemaining)
+	}
+	return merged
+}
+
+// applySegmentOptions pads the segment and reports whether it should still be
+// acted on. MinRemaining needs the video duration reported by SponsorBlock and
+// is not applied when it is unknown.
+func applySegmentOptions(s *Segment, options config.SegmentOptions, videoDuration float64) bool {
+	if videoDuration > 0 && options.MinRemaining > 0 &&
+		videoDuration-s.End < options.MinRemaining {
+		return false
+	}
+
+	s.Start += options.StartPadding
+	s.End -= options.EndPadding
+
+	duration := s.End - s.Start
+	return duration > 0 && duration >= options.MinDuration
+}
+
+// mergeSegments merges overlapping and close segments that share an action
+func mergeSegments(typedSegments []sponsorBlockSegment, action string) []Segment {
+	segments := make([]Segment, 0, len(typedSegments))
+
 	// Sort by end time
 	sort.Slice(typedSegments, func(i, j int) bool {
 		return typedSegments[i].Segment[1] < typedSegments[j].Segment[1]
@@ -167,12 +311,12 @@ func (a *APIHelper) processSegments(data map[string]interface{}) ([]Segment, boo
 
 	// Combine close segments
 	for _, s := range typedSegments {
-		ignoreTTL = ignoreTTL && s.Locked == 1
-
 		segment := Segment{
-			Start: s.Segment[0],
-			End:   s.Segment[1],
-			UUIDs: []string{s.UUID},
+			Start:      s.Segment[0],
+			End:        s.Segment[1],
+			UUIDs:      []string{s.UUID},
+			Categories: []string{s.Category},
+			Action:     action,
 		}
 
 		if len(segments) > 0 {
@@ -180,7 +324,11 @@ func (a *APIHelper) processSegments(data map[string]interface{}) ([]Segment, boo
 			if segment.Start-last.End < 1 {
 				// Less than 1 second apart, combine them
 				segment.Start = last.Start
+				if last.End > segment.End {
+					segment.End = last.End
+				}
 				segment.UUIDs = append(segment.UUIDs, last.UUIDs...)
+				segment.Categories = appendUnique(last.Categories, s.Category)
 				segments = segments[:len(segments)-1]
 			}
 		}
@@ -188,12 +336,22 @@ func (a *APIHelper) processSegments(data map[string]interface{}) ([]Segment, boo
 		segments = append(segments, segment)
 	}
 
-	return segments, ignoreTTL, nil
+	return segments
+}
+
+// appendUnique appends value to values unless it is already present
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
 }
 
 // MarkViewedSegments marks segments as viewed in SponsorBlock
 func (a *APIHelper) MarkViewedSegments(ctx context.Context, uuids []string) error {
-	if !a.cfg.SponsorBlock.SkipCountTracking {
+	if cfg, _ := a.settings(); !cfg.SkipCountTracking {
 		return nil
 	}
 
@@ -202,7 +360,7 @@ func (a *APIHelper) MarkViewedSegments(ctx context.Context, uuids []string) erro
 		params.Add("UUID", uuid)
 
 		req, err := http.NewRequestWithContext(ctx, "POST",
-			constants.SponsorBlockAPI+"viewedVideoSponsorTime/", nil)
+			constants.SponsorBlockAPI+"/viewedVideoSponsorTime/", nil)
 		if err != nil {
 			return err
 		}
@@ -224,11 +382,12 @@ func (a *APIHelper) MarkViewedSegments(ctx context.Context, uuids []string) erro
 func (a *APIHelper) getChannelID(ctx context.Context, videoID string) (string, error) {
 	params := url.Values{}
 	params.Add("id", videoID)
-	params.Add("key", a.cfg.YouTube.APIKey)
+	cfg, _ := a.settings()
+	params.Add("key", cfg.APIKey)
 	params.Add("part", "snippet")
 
 	req, err := http.NewRequestWithContext(ctx, "GET",
-		constants.YouTubeAPI+"videos", nil)
+		constants.YouTubeAPI+"/videos", nil)
 	if err != nil {
 		return "", err
 	}
@@ -261,7 +420,182 @@ func (a *APIHelper) getChannelID(ctx context.Context, videoID string) (string, e
 	return response.Items[0].Snippet.ChannelID, nil
 }
 
-// DiscoverYouTubeDevices discovers YouTube devices using DIAL
+// ChannelSearchResult is a channel found by SearchChannels
+type ChannelSearchResult struct {
+	ID              string
+	Title           string
+	SubscriberCount string
+}
+
+// SearchChannels searches for YouTube channels
+func (a *APIHelper) SearchChannels(ctx context.Context, query string) ([]ChannelSearchResult, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	cfg, _ := a.settings()
+	params.Add("key", cfg.APIKey)
+	params.Add("part", "snippet")
+	params.Add("type", "channel")
+	params.Add("maxResults", "5")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"/search", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("User-Agent", constants.UserAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("channel search failed: %s", resp.Status)
+	}
+
+	var data struct {
+		Items []struct {
+			Snippet struct {
+				ChannelID   string `json:"channelId"`
+				ChannelName string `json:"channelTitle"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	channels := make([]ChannelSearchResult, 0, len(data.Items))
+	for _, item := range data.Items {
+		// Get channel statistics
+		stats, err := a.getChannelStats(ctx, item.Snippet.ChannelID)
+		if err != nil {
+			continue
+		}
+
+		channels = append(channels, ChannelSearchResult{
+			ID:              item.Snippet.ChannelID,
+			Title:           item.Snippet.ChannelName,
+			SubscriberCount: stats,
+		})
+	}
+
+	return channels, nil
+}
+
+// getChannelStats gets the subscriber count for a channel
+func (a *APIHelper) getChannelStats(ctx context.Context, channelID string) (string, error) {
+	params := url.Values{}
+	params.Add("id", channelID)
+	cfg, _ := a.settings()
+	params.Add("key", cfg.APIKey)
+	params.Add("part", "statistics")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", constants.YouTubeAPI+"/channels", nil)
+	if err != nil {
+		return "", err
+	}
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("User-Agent", constants.UserAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Items []struct {
+			Statistics struct {
+				HiddenSubscriberCount bool   `json:"hiddenSubscriberCount"`
+				SubscriberCount       string `json:"subscriberCount"`
+			} `json:"statistics"`
+		} `json:"items"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", err
+	}
+
+	if len(data.Items) == 0 {
+		return "", fmt.Errorf("channel not found")
+	}
+
+	stats := data.Items[0].Statistics
+	if stats.HiddenSubscriberCount {
+		return "Hidden", nil
+	}
+
+	return stats.SubscriberCount, nil
+}
+
+// ChannelByHandle looks up the channel with an exact @handle. It reports
+// whether such a channel exists.
+func (a *APIHelper) ChannelByHandle(ctx context.Context, handle string) (ChannelSearchResult, bool, error) {
+	params := url.Values{}
+	params.Add("forHandle", handle)
+	cfg, _ := a.settings()
+	params.Add("key", cfg.APIKey)
+	params.Add("part", "snippet")
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		constants.YouTubeAPI+"/channels", nil)
+	if err != nil {
+		return ChannelSearchResult{}, false, err
+	}
+
+	req.URL.RawQuery = params.Encode()
+	req.Header.Set("User-Agent", constants.UserAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return ChannelSearchResult{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ChannelSearchResult{}, false, fmt.Errorf("channel lookup failed: %s", resp.Status)
+	}
+
+	var response struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Snippet struct {
+				Title string `json:"title"`
+			} `json:"snippet"`
+		} `json:"items"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return ChannelSearchResult{}, false, err
+	}
+
+	if len(response.Items) == 0 {
+		return ChannelSearchResult{}, false, nil
+	}
+
+	return ChannelSearchResult{
+		ID:    response.Items[0].ID,
+		Title: response.Items[0].Snippet.Title,
+	}, true, nil
+}
+
+// DiscoverYouTubeDevices discovers YouTube devices using DIAL on the network
+// interfaces selected in the config
 func (a *APIHelper) DiscoverYouTubeDevices(ctx context.Context) ([]dial.Device, error) {
-	return dial.Discover(ctx, a.httpClient)
+	cfg, _ := a.settings()
+	return dial.Discover(ctx, a.httpClient, DiscoveryFilter(cfg))
+}
+
+// DiscoveryFilter returns the interface filter of the discovery config
+func DiscoveryFilter(cfg *config.Config) dial.InterfaceFilter {
+	if cfg.Discovery == nil {
+		return dial.InterfaceFilter{}
+	}
+	return dial.InterfaceFilter{
+		Allow: cfg.Discovery.Interfaces,
+		Deny:  cfg.Discovery.ExcludeInterfaces,
+	}
 }
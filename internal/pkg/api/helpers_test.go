@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+)
+
+// recordedRequest is a request received by the fake SponsorBlock server
+type recordedRequest struct {
+	method string
+	path   string
+	query  url.Values
+}
+
+// fakeSponsorBlock serves SponsorBlock API requests and records them. The
+// returned client sends every request to the server.
+func fakeSponsorBlock(t *testing.T, segments interface{}) (*http.Client, func() []recordedRequest) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests []recordedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, recordedRequest{r.Method, r.URL.Path, r.URL.Query()})
+		mu.Unlock()
+		json.NewEncoder(w).Encode(segments)
+	}))
+	t.Cleanup(server.Close)
+
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: redirectTransport{target}}
+	return client, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+// redirectTransport sends requests to target, keeping their path and query
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// apiPath returns the request path of a SponsorBlock endpoint
+func apiPath(t *testing.T, endpoint string) string {
+	t.Helper()
+	base, err := url.Parse(constants.SponsorBlockAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base.Path + endpoint
+}
+
+func TestGetSegmentsRequest(t *testing.T) {
+	videoID := "dQw4w9WgXcQ"
+	client, requests := fakeSponsorBlock(t, []map[string]interface{}{{
+		"videoID": videoID,
+		"segments": []map[string]interface{}{
+			{"segment": []float64{10, 20}, "UUID": "a", "category": "sponsor", "locked": 1},
+			{"segment": []float64{30, 40}, "UUID": "b", "category": "intro", "locked": 1},
+		},
+	}})
+
+	cfg := &config.Config{
+		SkipCategories:  []string{"sponsor", "intro"},
+		CategoryActions: map[string]string{"outro": constants.ActionMute, "intro": constants.ActionIgnore},
+	}
+	segments, _, err := NewAPIHelper(cfg, client).GetSegments(context.Background(), videoID)
+	if err != nil {
+		t.Fatalf("GetSegments: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("got %d requests, want 1", len(got))
+	}
+	// The first 4 characters of the SHA-256 of the video ID
+	if want := apiPath(t, "/skipSegments/5f6b"); got[0].path != want {
+		t.Errorf("path = %q, want %q", got[0].path, want)
+	}
+	if want := []string{"outro", "sponsor"}; !reflect.DeepEqual(got[0].query["category"], want) {
+		t.Errorf("category params = %q, want %q", got[0].query["category"], want)
+	}
+	if got[0].query.Get("actionType") != constants.SponsorBlockActionType {
+		t.Errorf("actionType = %q", got[0].query.Get("actionType"))
+	}
+
+	if len(segments) != 1 || segments[0].Start != 10 || segments[0].Action != constants.ActionSkip {
+		t.Errorf("segments = %+v, want the sponsor segment only", segments)
+	}
+}
+
+func TestProcessSegmentsAppliesOptionsAfterMerging(t *testing.T) {
+	cfg := &config.Config{
+		SegmentOptions: map[string]config.SegmentOptions{
+			"sponsor":   {MinDuration: 5, StartPadding: 1},
+			"selfpromo": {MinDuration: 5, StartPadding: 2},
+			"outro":     {MinRemaining: 30},
+		},
+	}
+	actions := map[string]string{
+		"sponsor":   constants.ActionSkip,
+		"selfpromo": constants.ActionSkip,
+		"outro":     constants.ActionSkip,
+	}
+	data := map[string]interface{}{"segments": []interface{}{
+		// Too short on their own, long enough once merged
+		map[string]interface{}{"segment": []interface{}{10.0, 13.0}, "UUID": "a", "category": "sponsor", "videoDuration": 100.0},
+		map[string]interface{}{"segment": []interface{}{13.5, 17.0}, "UUID": "b", "category": "selfpromo", "videoDuration": 100.0},
+		// Ends too close to the end of the video
+		map[string]interface{}{"segment": []interface{}{80.0, 90.0}, "UUID": "c", "category": "outro", "videoDuration": 100.0},
+	}}
+
+	segments, _, err := (&APIHelper{}).processSegments(cfg, data, actions)
+	if err != nil {
+		t.Fatalf("processSegments: %v", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("segments = %+v, want the merged segment only", segments)
+	}
+	if segments[0].Start != 11 || segments[0].End != 17 {
+		t.Errorf("merged segment = %v-%v, want 11-17", segments[0].Start, segments[0].End)
+	}
+
+	// Without a video duration min_remaining is not applied
+	for _, s := range data["segments"].([]interface{}) {
+		delete(s.(map[string]interface{}), "videoDuration")
+	}
+	segments, _, _ = (&APIHelper{}).processSegments(cfg, data, actions)
+	if len(segments) != 2 {
+		t.Errorf("segments without a video duration = %+v, want 2", segments)
+	}
+}
+
+func TestMarkViewedSegmentsRequest(t *testing.T) {
+	client, requests := fakeSponsorBlock(t, nil)
+
+	cfg := &config.Config{SkipCountTracking: true}
+	if err := NewAPIHelper(cfg, client).MarkViewedSegments(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("MarkViewedSegments: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2", len(got))
+	}
+	for i, uuid := range []string{"a", "b"} {
+		if got[i].method != http.MethodPost || got[i].path != apiPath(t, "/viewedVideoSponsorTime/") {
+			t.Errorf("request %d = %s %s", i, got[i].method, got[i].path)
+		}
+		if got[i].query.Get("UUID") != uuid {
+			t.Errorf("request %d UUID = %q, want %q", i, got[i].query.Get("UUID"), uuid)
+		}
+	}
+}
+
+func TestChannelByHandle(t *testing.T) {
+	client, requests := fakeSponsorBlock(t, map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"id": "UCuAXFkgsw1L7xaCfnd5JJOw", "snippet": map[string]interface{}{"title": "Rick Astley"}},
+		},
+	})
+
+	cfg := &config.Config{APIKey: "key"}
+	channel, ok, err := NewAPIHelper(cfg, client).ChannelByHandle(context.Background(), "@RickAstleyYT")
+	if err != nil || !ok {
+		t.Fatalf("ChannelByHandle = %v, %v", ok, err)
+	}
+	if channel.ID != "UCuAXFkgsw1L7xaCfnd5JJOw" || channel.Title != "Rick Astley" {
+		t.Errorf("channel = %+v", channel)
+	}
+	if got := requests()[0].query.Get("forHandle"); got != "@RickAstleyYT" {
+		t.Errorf("forHandle = %q", got)
+	}
+
+	client, _ = fakeSponsorBlock(t, map[string]interface{}{})
+	if _, ok, err := NewAPIHelper(cfg, client).ChannelByHandle(context.Background(), "@nobody"); ok || err != nil {
+		t.Errorf("ChannelByHandle of an unknown handle = %v, %v", ok, err)
+	}
+}
+
+func TestSearchChannels(t *testing.T) {
+	// The same response serves the search and the statistics request
+	client, requests := fakeSponsorBlock(t, map[string]interface{}{
+		"items": []map[string]interface{}{{
+			"snippet":    map[string]interface{}{"channelId": "UCuAXFkgsw1L7xaCfnd5JJOw", "channelTitle": "Rick Astley"},
+			"statistics": map[string]interface{}{"subscriberCount": "4200000"},
+		}},
+	})
+
+	cfg := &config.Config{APIKey: "key"}
+	channels, err := NewAPIHelper(cfg, client).SearchChannels(context.Background(), "rick astley")
+	if err != nil {
+		t.Fatalf("SearchChannels: %v", err)
+	}
+	want := []ChannelSearchResult{{ID: "UCuAXFkgsw1L7xaCfnd5JJOw", Title: "Rick Astley", SubscriberCount: "4200000"}}
+	if !reflect.DeepEqual(channels, want) {
+		t.Errorf("channels = %+v, want %+v", channels, want)
+	}
+
+	got := requests()
+	if len(got) != 2 || got[0].query.Get("q") != "rick astley" || got[1].query.Get("id") != "UCuAXFkgsw1L7xaCfnd5JJOw" {
+		t.Errorf("requests = %+v", got)
+	}
+	if got[0].query.Get("key") != "key" {
+		t.Errorf("key = %q, want the API key of the config", got[0].query.Get("key"))
+	}
+}
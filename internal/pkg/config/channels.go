@@ -0,0 +1,46 @@
+package config
+
+import "github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+
+// HasChannel reports whether a channel ID is whitelisted
+func (c *Config) HasChannel(channelID string) bool {
+	for _, channel := range c.ChannelWhitelist {
+		if channel.ID == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddChannel appends a channel to the whitelist unless it is already
+// whitelisted, and reports whether it was added
+func (c *Config) AddChannel(channel types.ChannelInfo) bool {
+	if channel.ID == "" || c.HasChannel(channel.ID) {
+		return false
+	}
+	c.ChannelWhitelist = append(c.ChannelWhitelist, channel)
+	return true
+}
+
+// RemoveChannel removes a channel from the whitelist and reports whether it
+// was whitelisted
+func (c *Config) RemoveChannel(channelID string) bool {
+	for i, channel := range c.ChannelWhitelist {
+		if channel.ID == channelID {
+			c.ChannelWhitelist = append(c.ChannelWhitelist[:i:i], c.ChannelWhitelist[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// MoveChannel swaps the whitelist entry at index i with the one at i+delta
+// and returns its new index
+func (c *Config) MoveChannel(i, delta int) int {
+	j := i + delta
+	if i < 0 || i >= len(c.ChannelWhitelist) || j < 0 || j >= len(c.ChannelWhitelist) {
+		return i
+	}
+	c.ChannelWhitelist[i], c.ChannelWhitelist[j] = c.ChannelWhitelist[j], c.ChannelWhitelist[i]
+	return j
+}
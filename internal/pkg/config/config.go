@@ -2,25 +2,53 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
 )
 
 // Config represents the application configuration
 type Config struct {
-	APIKey            string                   `json:"apikey"`
-	SkipCategories    []string                 `json:"skip_categories"`
-	ChannelWhitelist  []types.ChannelInfo      `json:"channel_whitelist"`
-	SkipCountTracking bool                     `json:"skip_count_tracking"`
-	Devices           []DeviceConfig           `json:"devices"`
-	Debug             bool                     `json:"debug"`
-	MuteAds           bool                     `json:"mute_ads"`
-	SkipAds           bool                     `json:"skip_ads"`
-	AutoPlay          bool                     `json:"auto_play"`
-	YouTube           types.YouTubeConfig      `json:"youtube"`
-	SponsorBlock      types.SponsorBlockConfig `json:"sponsorblock"`
-	JoinName          string                   `json:"join_name"`
+	APIKey            string                    `json:"apikey"`
+	SkipCategories    []string                  `json:"skip_categories"`
+	ChannelWhitelist  []types.ChannelInfo       `json:"channel_whitelist"`
+	SkipCountTracking bool                      `json:"skip_count_tracking"`
+	Devices           []DeviceConfig            `json:"devices"`
+	Debug             bool                      `json:"debug"`
+	MuteAds           bool                      `json:"mute_ads"`
+	SkipAds           bool                      `json:"skip_ads"`
+	AutoPlay          bool                      `json:"auto_play"`
+	YouTube           *types.YouTubeConfig      `json:"youtube,omitempty"`
+	SponsorBlock      *types.SponsorBlockConfig `json:"sponsorblock,omitempty"`
+	JoinName          string                    `json:"join_name"`
+	// CategoryActions assigns an action other than skipping to a category,
+	// keyed by category ID. It is layered over SkipCategories.
+	CategoryActions map[string]string         `json:"category_actions,omitempty"`
+	ChannelRules    map[string]ChannelRule    `json:"channel_rules,omitempty"`
+	SegmentOptions  map[string]SegmentOptions `json:"segment_options,omitempty"`
+	Profiles        map[string]Overrides      `json:"profiles,omitempty"`
+	Schedules       []Schedule                `json:"schedules,omitempty"`
+	Discovery       *DiscoveryConfig          `json:"discovery,omitempty"`
+	// CalibrationVideo is the ID of the video played to calibrate device
+	// offsets, for when the default one is unavailable in a region
+	CalibrationVideo string `json:"calibration_video,omitempty"`
+
+	// ConfigFile and DataDir record where the config was loaded from and where
+	// persistent state is kept. They are never saved to the config file.
+	ConfigFile string `json:"config_file"`
+	DataDir    string `json:"data_dir"`
+
+	// sources records values overridden from the environment
+	sources Sources
+	// warnings holds the problems found while loading, such as deprecated
+	// keys and unknown environment variables, reported by Validate
+	warnings Problems
+	// fileValues is the config without environment overrides as JSON, which
+	// Save writes in place of the overridden values
+	fileValues []byte
 }
 
 // DeviceConfig represents a device configuration
@@ -28,21 +56,241 @@ type DeviceConfig struct {
 	Name     string  `json:"name"`
 	Offset   float64 `json:"offset"`
 	ScreenID string  `json:"screen_id"`
+	Overrides
+}
+
+// DiscoveryConfig selects the network interfaces searched for devices by
+// name. Names may be glob patterns such as "docker*".
+type DiscoveryConfig struct {
+	// Interfaces lists the interfaces to search; empty searches every
+	// interface that is up and supports multicast
+	Interfaces []string `json:"interfaces,omitempty"`
+	// ExcludeInterfaces lists interfaces never searched
+	ExcludeInterfaces []string `json:"exclude_interfaces,omitempty"`
+}
+
+// Overrides holds global options that can be overridden per device, per
+// profile or per schedule.
+//
+// A field that is absent from the config file (nil) inherits the value it
+// overrides; any value that is present, including an empty list, replaces it.
+// Lists and maps are pointers like the other fields, so an empty one is
+// told apart from an absent one and survives a save.
+type Overrides struct {
+	SkipCategories    *[]string                  `json:"skip_categories,omitempty"`
+	ChannelWhitelist  *[]types.ChannelInfo       `json:"channel_whitelist,omitempty"`
+	ChannelRules      *map[string]ChannelRule    `json:"channel_rules,omitempty"`
+	SegmentOptions    *map[string]SegmentOptions `json:"segment_options,omitempty"`
+	CategoryActions   *map[string]string         `json:"category_actions,omitempty"`
+	SkipCountTracking *bool                      `json:"skip_count_tracking,omitempty"`
+	MuteAds           *bool                      `json:"mute_ads,omitempty"`
+	SkipAds           *bool                      `json:"skip_ads,omitempty"`
+	AutoPlay          *bool                      `json:"auto_play,omitempty"`
+	JoinName          *string                    `json:"join_name,omitempty"`
+}
+
+// ForDevice returns a copy of the config with the overrides of the given
+// device applied. Devices in the copy are reduced to the given device.
+func (c *Config) ForDevice(device DeviceConfig) *Config {
+	effective := c.WithOverrides(device.Overrides)
+	effective.Devices = []DeviceConfig{device}
+	return effective
+}
+
+// WithOverrides returns a copy of the config with the given overrides applied
+func (c *Config) WithOverrides(o Overrides) *Config {
+	effective := *c
+
+	if o.SkipCategories != nil {
+		effective.SkipCategories = *o.SkipCategories
+	}
+	if o.ChannelWhitelist != nil {
+		effective.ChannelWhitelist = *o.ChannelWhitelist
+	}
+	if o.ChannelRules != nil {
+		effective.ChannelRules = *o.ChannelRules
+	}
+	if o.SegmentOptions != nil {
+		effective.SegmentOptions = *o.SegmentOptions
+	}
+	if o.CategoryActions != nil {
+		effective.CategoryActions = *o.CategoryActions
+	}
+	if o.SkipCountTracking != nil {
+		effective.SkipCountTracking = *o.SkipCountTracking
+	}
+	if o.MuteAds != nil {
+		effective.MuteAds = *o.MuteAds
+	}
+	if o.SkipAds != nil {
+		effective.SkipAds = *o.SkipAds
+	}
+	if o.AutoPlay != nil {
+		effective.AutoPlay = *o.AutoPlay
+	}
+	if o.JoinName != nil {
+		effective.JoinName = *o.JoinName
+	}
+
+	return &effective
 }
 
-// LoadConfig loads the configuration from config.json
-func LoadConfig() (*Config, error) {
-	// Read config file
-	data, err := os.ReadFile("config.json")
+// ChannelRule overrides the category actions for videos from a single channel,
+// keyed by channel ID in Config.ChannelRules
+type ChannelRule struct {
+	Name string `json:"name,omitempty"`
+	// Exclusive replaces the global categories instead of layering over them
+	Exclusive  bool              `json:"exclusive,omitempty"`
+	Categories map[string]string `json:"categories"`
+}
+
+// Apply returns the category actions for the rule's channel given the global
+// category actions. The base map is not modified.
+func (r ChannelRule) Apply(base map[string]string) map[string]string {
+	actions := make(map[string]string, len(base)+len(r.Categories))
+	if !r.Exclusive {
+		for category, action := range base {
+			actions[category] = action
+		}
+	}
+	for category, action := range r.Categories {
+		actions[category] = action
+	}
+	return actions
+}
+
+// DefaultSegmentOptions is the key of Config.SegmentOptions used for
+// categories without an entry of their own
+const DefaultSegmentOptions = "*"
+
+// SegmentOptions tunes which segments of a category are acted on and where.
+// All values are in seconds.
+type SegmentOptions struct {
+	// MinDuration ignores segments shorter than this
+	MinDuration float64 `json:"min_duration,omitempty"`
+	// StartPadding delays the start of the segment by this much
+	StartPadding float64 `json:"start_padding,omitempty"`
+	// EndPadding ends the segment this much before its real end
+	EndPadding float64 `json:"end_padding,omitempty"`
+	// MinRemaining ignores segments ending less than this before the end of
+	// the video, so seeking does not trigger autoplay. It is not applied to
+	// videos whose duration SponsorBlock does not report.
+	MinRemaining float64 `json:"min_remaining,omitempty"`
+}
+
+// SegmentOptionsFor returns the segment options for a category, falling back
+// to the default entry
+func (c *Config) SegmentOptionsFor(category string) SegmentOptions {
+	if options, ok := c.SegmentOptions[category]; ok {
+		return options
+	}
+	return c.SegmentOptions[DefaultSegmentOptions]
+}
+
+// NewConfig returns a config with default values that will be saved to
+// configFile, for first runs without a config file. Empty paths are resolved
+// with ResolveConfigFile and ResolveDataDir.
+func NewConfig(configFile, dataDir string) (*Config, error) {
+	configFile, err := ResolveConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err = ResolveDataDir(dataDir)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse config
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	return &Config{
+		SkipCategories:    []string{"sponsor"},
+		SkipCountTracking: true,
+		MuteAds:           true,
+		SkipAds:           true,
+		AutoPlay:          true,
+		JoinName:          constants.AppDirName,
+		Devices:           []DeviceConfig{},
+		ChannelWhitelist:  []types.ChannelInfo{},
+		ConfigFile:        configFile,
+		DataDir:           dataDir,
+	}, nil
+}
+
+// LoadConfig loads the configuration from configFile, applies SBTV_*
+// environment overrides and creates the data directory dataDir. Empty paths
+// are resolved with ResolveConfigFile and ResolveDataDir. A missing config
+// file is only an error if no environment overrides are set, so containers
+// can be configured from the environment alone.
+func LoadConfig(configFile, dataDir string) (*Config, error) {
+	cfg, err := NewConfig(configFile, dataDir)
+	if err != nil {
 		return nil, err
 	}
+	configFile, dataDir = cfg.ConfigFile, cfg.DataDir
 
-	return &cfg, nil
+	// Read and parse config file
+	sources := make(Sources)
+	data, err := os.ReadFile(configFile)
+	switch {
+	case err == nil:
+		if data, err = toJSON(data, FormatFromPath(configFile)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+		cfg = &Config{}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", configFile, err)
+		}
+		fileSources(sources, data, configFile)
+	case !os.IsNotExist(err) || !hasEnvOverrides(os.Environ()):
+		return nil, err
+	}
+
+	// Keep the values of the file for Save, so overrides are never written
+	fileCfg := cfg.Clone()
+	fileCfg.sources = copySources(sources)
+	fileCfg.Normalize()
+	if cfg.fileValues, err = json.Marshal(fileCfg); err != nil {
+		return nil, err
+	}
+
+	// Apply environment overrides, which replace everything below their path
+	envSources, envWarnings, err := ApplyEnv(cfg, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	for path, source := range envSources {
+		for existing := range sources {
+			if strings.HasPrefix(existing, path+".") || strings.HasPrefix(existing, path+"[") {
+				delete(sources, existing)
+			}
+		}
+		sources[path] = source
+	}
+	cfg.sources = sources
+	cfg.warnings = append(envWarnings, cfg.Normalize()...)
+
+	// Create data directory
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	cfg.ConfigFile = configFile
+	cfg.DataDir = dataDir
+
+	return cfg, nil
+}
+
+// Sources returns where the values of the config came from, by JSON path
+func (c *Config) Sources() Sources {
+	return c.sources
+}
+
+// hasEnvOverrides reports whether environ overrides any config key other
+// than the config file and data directory locations
+func hasEnvOverrides(environ []string) bool {
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, EnvPrefix) && name != EnvConfigFile && name != EnvDataDir {
+			return true
+		}
+	}
+	return false
 }
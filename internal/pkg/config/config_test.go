@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEmptyOverrideSurvivesSave(t *testing.T) {
+	configs := map[string]string{
+		"config.json": `{
+			"skip_categories": ["sponsor"],
+			"devices": [
+				{"screen_id": "muted", "skip_categories": [], "channel_rules": {}},
+				{"screen_id": "inherits"}
+			]
+		}`,
+		"config.yaml": "skip_categories: [sponsor]\n" +
+			"devices:\n" +
+			"  - screen_id: muted\n" +
+			"    skip_categories: []\n" +
+			"    channel_rules: {}\n" +
+			"  - screen_id: inherits\n",
+		"config.toml": "skip_categories = [\"sponsor\"]\n" +
+			"\n" +
+			"[[devices]]\n" +
+			"screen_id = \"muted\"\n" +
+			"skip_categories = []\n" +
+			"channel_rules = {}\n" +
+			"\n" +
+			"[[devices]]\n" +
+			"screen_id = \"inherits\"\n",
+	}
+
+	for name, content := range configs {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, name)
+			if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			check := func(stage string) {
+				cfg, err := LoadConfig(file, filepath.Join(dir, "data"))
+				if err != nil {
+					t.Fatalf("%s: LoadConfig: %v", stage, err)
+				}
+				if len(cfg.Devices) != 2 {
+					t.Fatalf("%s: got %d devices", stage, len(cfg.Devices))
+				}
+				muted := cfg.ForDevice(cfg.Devices[0])
+				if muted.SkipCategories == nil || len(muted.SkipCategories) != 0 {
+					t.Errorf("%s: muted device skip_categories = %#v, want empty", stage, muted.SkipCategories)
+				}
+				if muted.ChannelRules == nil || len(muted.ChannelRules) != 0 {
+					t.Errorf("%s: muted device channel_rules = %#v, want empty", stage, muted.ChannelRules)
+				}
+				inherits := cfg.ForDevice(cfg.Devices[1])
+				if want := []string{"sponsor"}; !reflect.DeepEqual(inherits.SkipCategories, want) {
+					t.Errorf("%s: inheriting device skip_categories = %v, want %v", stage, inherits.SkipCategories, want)
+				}
+
+				if err := Save(cfg); err != nil {
+					t.Fatalf("%s: Save: %v", stage, err)
+				}
+			}
+
+			check("before save")
+			check("after save")
+			if t.Failed() {
+				data, _ := os.ReadFile(file)
+				t.Logf("saved file:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestCategoryActionsOverride(t *testing.T) {
+	cfg := &Config{
+		SkipCategories:  []string{"sponsor"},
+		CategoryActions: map[string]string{"intro": "mute"},
+		Devices: []DeviceConfig{
+			{ScreenID: "a", Overrides: Overrides{CategoryActions: &map[string]string{"outro": "skip"}}},
+			{ScreenID: "b"},
+		},
+	}
+
+	if got := cfg.ForDevice(cfg.Devices[0]).CategoryActions; !reflect.DeepEqual(got, map[string]string{"outro": "skip"}) {
+		t.Errorf("overridden category_actions = %v", got)
+	}
+	if got := cfg.ForDevice(cfg.Devices[1]).CategoryActions; !reflect.DeepEqual(got, cfg.CategoryActions) {
+		t.Errorf("inherited category_actions = %v", got)
+	}
+}
+
+func TestUnskippableCategoriesAreRejected(t *testing.T) {
+	cfg := &Config{
+		SkipCategories:  []string{"sponsor", "poi_highlight"},
+		CategoryActions: map[string]string{"exclusive_access": "mute", "intro": "ignore"},
+		Devices: []DeviceConfig{
+			{ScreenID: "a", Overrides: Overrides{CategoryActions: &map[string]string{"poi_highlight": "ignore"}}},
+		},
+	}
+
+	var errors []string
+	for _, problem := range Validate(cfg) {
+		if problem.Severity == SeverityError {
+			errors = append(errors, problem.Path)
+		}
+	}
+	want := []string{"skip_categories[1]", "category_actions.exclusive_access"}
+	if !reflect.DeepEqual(errors, want) {
+		t.Errorf("errors at %q, want %q", errors, want)
+	}
+}
+
+func TestTemplateValidates(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "config.json.template"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(file, filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if problems := Validate(cfg); problems.HasErrors() {
+		t.Errorf("template does not validate: %v", problems)
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+// DefaultOffset is the offset in seconds given to newly added devices
+const DefaultOffset = 0.0
+
+// HasDevice reports whether a device with the given screen ID is configured
+func (c *Config) HasDevice(screenID string) bool {
+	for _, device := range c.Devices {
+		if device.ScreenID == screenID {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDevice appends a device unless one with the same screen ID is already
+// configured, and reports whether it was added
+func (c *Config) AddDevice(device DeviceConfig) bool {
+	if device.ScreenID == "" || c.HasDevice(device.ScreenID) {
+		return false
+	}
+	c.Devices = append(c.Devices, device)
+	return true
+}
+
+// RemoveDevice removes the device with the given screen ID and reports
+// whether it was configured
+func (c *Config) RemoveDevice(screenID string) bool {
+	for i, device := range c.Devices {
+		if device.ScreenID == screenID {
+			c.Devices = append(c.Devices[:i:i], c.Devices[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
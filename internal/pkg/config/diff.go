@@ -0,0 +1,125 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change is a value that differs between two configs
+type Change struct {
+	// Path is the JSON path of the value, e.g. devices[0].screen_id
+	Path string
+	// Old and New are the JSON encodings of the value, empty when the value
+	// is absent from that config
+	Old string
+	New string
+}
+
+// Clone returns a deep copy of the config
+func (c *Config) Clone() *Config {
+	clone := *c
+	v := reflect.ValueOf(&clone).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Field(i); field.CanSet() {
+			field.Set(deepCopy(field))
+		}
+	}
+	clone.sources = copySources(c.sources)
+	clone.warnings = append(Problems(nil), c.warnings...)
+	return &clone
+}
+
+// copySources returns a copy of sources, nil for nil
+func copySources(sources Sources) Sources {
+	if sources == nil {
+		return nil
+	}
+	copied := make(Sources, len(sources))
+	for path, source := range sources {
+		copied[path] = source
+	}
+	return copied
+}
+
+// deepCopy copies v including everything it points to
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Elem().Type())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < copied.NumField(); i++ {
+			if field := copied.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	}
+	return v
+}
+
+// Diff returns the values that differ between two configs, in the field
+// order of to followed by the values only present in from. Empty lists and
+// maps count as absent.
+func Diff(from, to *Config) []Change {
+	old := make(map[string]string)
+	for _, setting := range from.Settings() {
+		if !isUnset(setting) {
+			old[setting.Path] = setting.Value
+		}
+	}
+
+	changes := make([]Change, 0)
+	seen := make(map[string]bool)
+	for _, setting := range to.Settings() {
+		if isUnset(setting) {
+			continue
+		}
+		seen[setting.Path] = true
+		if old[setting.Path] != setting.Value {
+			changes = append(changes, Change{Path: setting.Path, Old: old[setting.Path], New: setting.Value})
+		}
+	}
+	for _, setting := range from.Settings() {
+		if !seen[setting.Path] && !isUnset(setting) {
+			changes = append(changes, Change{Path: setting.Path, Old: setting.Value})
+		}
+	}
+	return changes
+}
+
+// isUnset reports whether a setting is a top-level empty list or map, which
+// means the same as an unset one. Below the top level an empty list can
+// override an inherited one, so it counts as set.
+func isUnset(setting Setting) bool {
+	topLevel := !strings.ContainsAny(setting.Path, ".[")
+	return topLevel && (setting.Value == "[]" || setting.Value == "{}")
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvPrefix is the prefix of environment variables overriding config keys
+	EnvPrefix = "SBTV_"
+
+	// envFileSuffix marks an environment variable naming a file to read the
+	// value from, e.g. SBTV_APIKEY_FILE=/run/secrets/apikey
+	envFileSuffix = "_FILE"
+)
+
+// Sources records where config values came from, keyed by JSON path such as
+// devices[0].screen_id. Values without an entry for their path or a parent
+// path are defaults.
+type Sources map[string]string
+
+// EnvName returns the environment variable overriding the value at a JSON path
+func EnvName(path string) string {
+	name := strings.NewReplacer("[", "_", "]", "", ".", "_").Replace(path)
+	return EnvPrefix + strings.ToUpper(name)
+}
+
+// ApplyEnv overrides config values from SBTV_* variables in environ, which is
+// in os.Environ format.
+//
+// Every key can be set by its upper-cased JSON path with dots and indexes
+// replaced by underscores: SBTV_APIKEY, SBTV_YOUTUBE_APIKEY,
+// SBTV_DEVICES_0_SCREEN_ID. Lists and maps can also be set as a whole with a
+// JSON value, e.g. SBTV_SKIP_CATEGORIES='["sponsor","intro"]'. Appending
+// _FILE reads the value from the named file instead, for mounted secrets.
+// Variables matching no config key are returned as warnings.
+func ApplyEnv(cfg *Config, environ []string) (Sources, Problems, error) {
+	sources := make(Sources)
+	v := &validator{}
+
+	// Apply in sorted order so the result does not depend on the environment order
+	entries := append([]string(nil), environ...)
+	sort.Strings(entries)
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, EnvPrefix)
+
+		path, err := setEnvValue(reflect.ValueOf(cfg).Elem(), key, value, "")
+		if err == errNoField && strings.HasSuffix(key, envFileSuffix) {
+			file := value
+			var data []byte
+			if data, err = os.ReadFile(file); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+			key = strings.TrimSuffix(key, envFileSuffix)
+			if path, err = setEnvValue(reflect.ValueOf(cfg).Elem(), key, value, ""); err == nil {
+				sources[path] = fmt.Sprintf("env %s (%s)", name, file)
+				continue
+			}
+		}
+		if err == errNoField {
+			v.warnf(name, "check the spelling or unset it", "environment variable does not match any config key, ignoring it")
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sources[path] = "env " + name
+	}
+
+	return sources, v.problems, nil
+}
+
+// errNoField is returned by setEnvValue when the key matches no field
+var errNoField = fmt.Errorf("no matching field")
+
+// setEnvValue sets the value addressed by key, an upper-cased and underscore
+// separated path below v, and returns its JSON path
+func setEnvValue(v reflect.Value, key, value, path string) (string, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setEnvValue(v.Elem(), key, value, path)
+
+	case reflect.Struct:
+		// Prefer the longest matching field, as keys can share prefixes
+		fields := jsonFields(v)
+		sort.Slice(fields, func(i, j int) bool { return len(fields[i].name) > len(fields[j].name) })
+		for _, field := range fields {
+			fieldKey := strings.ToUpper(field.name)
+			fieldPath := joinPath(path, field.name)
+			if key == fieldKey {
+				return fieldPath, setFromString(field.value, value)
+			}
+			if strings.HasPrefix(key, fieldKey+"_") {
+				p, err := setEnvValue(field.value, strings.TrimPrefix(key, fieldKey+"_"), value, fieldPath)
+				if err != errNoField {
+					return p, err
+				}
+			}
+		}
+		return "", errNoField
+
+	case reflect.Slice:
+		indexKey, rest, _ := strings.Cut(key, "_")
+		index, err := strconv.Atoi(indexKey)
+		if err != nil || index < 0 {
+			return "", errNoField
+		}
+		if index >= v.Len() {
+			grown := reflect.MakeSlice(v.Type(), index+1, index+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+		itemPath := fmt.Sprintf("%s[%d]", path, index)
+		if rest == "" {
+			return itemPath, setFromString(v.Index(index), value)
+		}
+		return setEnvValue(v.Index(index), rest, value, itemPath)
+	}
+
+	return "", errNoField
+}
+
+// setFromString parses value into v. Strings are taken as is, everything
+// else is parsed as JSON.
+func setFromString(v reflect.Value, value string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(value)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String {
+		v.Set(reflect.New(v.Type().Elem()))
+		v.Elem().SetString(value)
+		return nil
+	}
+
+	target := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	v.Set(target.Elem())
+	return nil
+}
+
+// jsonField is a struct field with its JSON name
+type jsonField struct {
+	name  string
+	value reflect.Value
+}
+
+// jsonFields returns the JSON-visible fields of a struct, flattening
+// embedded structs the way encoding/json does
+func jsonFields(v reflect.Value) []jsonField {
+	fields := make([]jsonField, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(v.Field(i))...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{name: name, value: v.Field(i)})
+	}
+	return fields
+}
+
+// joinPath appends a field name to a JSON path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,313 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a config file format
+type Format int
+
+const (
+	// FormatJSON is the default config format
+	FormatJSON Format = iota
+	// FormatYAML is selected by the .yaml and .yml extensions
+	FormatYAML
+	// FormatTOML is selected by the .toml extension
+	FormatTOML
+)
+
+// configFileNames are the config file names looked for, in order
+var configFileNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// FormatFromPath returns the format of a config file by its extension.
+// Unknown extensions are read as JSON.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// String returns the name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatYAML:
+		return "YAML"
+	case FormatTOML:
+		return "TOML"
+	default:
+		return "JSON"
+	}
+}
+
+// toJSON converts a config document in the given format to JSON
+func toJSON(data []byte, format Format) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yamlToJSON(data)
+	case FormatTOML:
+		return tomlToJSON(data)
+	default:
+		return data, nil
+	}
+}
+
+// fromJSON converts a JSON config document to the given format, keeping the
+// comments and key order of the layout
+func fromJSON(data []byte, format Format, l *layout) ([]byte, error) {
+	tree, err := parseOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := tree.(*object)
+	if !ok {
+		return nil, fmt.Errorf("config is not an object")
+	}
+	l.apply(root, "")
+
+	switch format {
+	case FormatYAML:
+		return encodeYAML(root, l)
+	case FormatTOML:
+		return encodeTOML(root, l)
+	default:
+		return encodeJSON(root)
+	}
+}
+
+// encodeJSON writes the tree as indented JSON in key order
+func encodeJSON(root *object) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := writeJSON(&compact, root); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "    "); err != nil {
+		return nil, err
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// writeJSON writes a tree value as compact JSON
+func writeJSON(out *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case *object:
+		out.WriteByte('{')
+		for i, key := range value.keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			encodedKey, _ := json.Marshal(key)
+			out.Write(encodedKey)
+			out.WriteByte(':')
+			if err := writeJSON(out, value.values[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case []interface{}:
+		out.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeJSON(out, item); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	}
+	return nil
+}
+
+// layoutOf returns the comments and key order of a config document
+func layoutOf(data []byte, format Format) *layout {
+	switch format {
+	case FormatYAML:
+		return yamlLayout(data)
+	case FormatTOML:
+		return tomlLayout(data)
+	default:
+		return newLayout()
+	}
+}
+
+// ConvertFile converts the config file at src to the format of dst, carrying
+// over comments and key order. Keys are copied as they are, without loading
+// them into a Config, so nothing is lost.
+func ConvertFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	srcFormat := FormatFromPath(src)
+	jsonData, err := toJSON(data, srcFormat)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", src, err)
+	}
+
+	out, err := fromJSON(jsonData, FormatFromPath(dst), layoutOf(data, srcFormat))
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(dst, out)
+}
+
+// object is a JSON object that keeps the order of its keys
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// parseOrdered decodes JSON into *object, []interface{} and scalar values,
+// with numbers kept as json.Number
+func parseOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return parseOrderedValue(dec)
+}
+
+func parseOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := &object{values: make(map[string]interface{})}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := parseOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := obj.values[key]; !exists {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		items := make([]interface{}, 0)
+		for dec.More() {
+			value, err := parseOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+
+	return tok, nil
+}
+
+// layout holds the comments and key order of a config document by JSON path
+type layout struct {
+	// head holds the comment lines above a key; the document comment is
+	// stored under the empty path
+	head map[string]string
+	// line holds the comment on the same line as a key
+	line map[string]string
+	// order holds the key order of the object at a path
+	order map[string][]string
+}
+
+func newLayout() *layout {
+	return &layout{
+		head:  make(map[string]string),
+		line:  make(map[string]string),
+		order: make(map[string][]string),
+	}
+}
+
+// addKey records key as the next key of the object at path
+func (l *layout) addKey(path, key string) {
+	for _, existing := range l.order[path] {
+		if existing == key {
+			return
+		}
+	}
+	l.order[path] = append(l.order[path], key)
+}
+
+// apply reorders the objects in the tree below path to the recorded order.
+// Keys without a recorded position keep their relative order after the
+// recorded ones.
+func (l *layout) apply(value interface{}, path string) {
+	switch value := value.(type) {
+	case *object:
+		if order, ok := l.order[path]; ok {
+			keys := make([]string, 0, len(value.keys))
+			for _, key := range order {
+				if _, ok := value.values[key]; ok {
+					keys = append(keys, key)
+				}
+			}
+			for _, key := range value.keys {
+				if !containsString(keys, key) {
+					keys = append(keys, key)
+				}
+			}
+			value.keys = keys
+		}
+		for _, key := range value.keys {
+			l.apply(value.values[key], joinPath(path, key))
+		}
+	case []interface{}:
+		for i, item := range value {
+			l.apply(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// commentLines splits a comment into lines without their comment markers
+func commentLines(comment string) []string {
+	if comment == "" {
+		return nil
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "#")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return lines
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
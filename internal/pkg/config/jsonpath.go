@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// orderedObject is a decoded JSON object that keeps the order of its keys
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// removedValue marks a value to leave out when encoding
+type removedValue struct{}
+
+// pathStep is a step of a JSON path: an object key, or an array index if
+// key is empty
+type pathStep struct {
+	key   string
+	index int
+}
+
+// parsePath splits a JSON path such as devices[0].screen_id into steps
+func parsePath(path string) []pathStep {
+	var steps []pathStep
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			steps = append(steps, pathStep{key: key})
+		}
+		for rest != "" {
+			indexText, next, _ := strings.Cut(rest, "]")
+			index, _ := strconv.Atoi(indexText)
+			steps = append(steps, pathStep{index: index})
+			rest = strings.TrimPrefix(next, "[")
+		}
+	}
+	return steps
+}
+
+// decodeOrdered decodes JSON into orderedObject, []interface{} and scalar
+// values, with numbers as json.Number
+func decodeOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeOrderedValue(dec)
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := &orderedObject{values: make(map[string]interface{})}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.values[key]; !ok {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		array := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := dec.Token()
+		return array, err
+	}
+	return tok, nil
+}
+
+// encodeOrdered writes a value decoded by decodeOrdered as JSON, leaving out
+// removed values
+func encodeOrdered(out *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case *orderedObject:
+		out.WriteByte('{')
+		first := true
+		for _, key := range value.keys {
+			if _, ok := value.values[key].(removedValue); ok {
+				continue
+			}
+			if !first {
+				out.WriteByte(',')
+			}
+			first = false
+			encodedKey, _ := json.Marshal(key)
+			out.Write(encodedKey)
+			out.WriteByte(':')
+			if err := encodeOrdered(out, value.values[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+
+	case []interface{}:
+		out.WriteByte('[')
+		first := true
+		for _, item := range value {
+			if _, ok := item.(removedValue); ok {
+				continue
+			}
+			if !first {
+				out.WriteByte(',')
+			}
+			first = false
+			if err := encodeOrdered(out, item); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %v: %w", value, err)
+		}
+		out.Write(encoded)
+	}
+	return nil
+}
+
+// lookupJSON returns the value at the end of steps
+func lookupJSON(value interface{}, steps []pathStep) (interface{}, bool) {
+	for _, step := range steps {
+		switch current := value.(type) {
+		case *orderedObject:
+			if step.key == "" {
+				return nil, false
+			}
+			next, ok := current.values[step.key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			if step.key != "" || step.index < 0 || step.index >= len(current) {
+				return nil, false
+			}
+			value = current[step.index]
+		default:
+			return nil, false
+		}
+		if _, ok := value.(removedValue); ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setJSON replaces the value at the end of steps. Nothing is changed if the
+// parent of the value does not exist.
+func setJSON(value interface{}, steps []pathStep, replacement interface{}) {
+	if len(steps) == 0 {
+		return
+	}
+	parent, ok := lookupJSON(value, steps[:len(steps)-1])
+	if !ok {
+		return
+	}
+
+	last := steps[len(steps)-1]
+	switch parent := parent.(type) {
+	case *orderedObject:
+		if last.key == "" {
+			return
+		}
+		if _, ok := parent.values[last.key]; !ok {
+			if _, removed := replacement.(removedValue); removed {
+				return
+			}
+			parent.keys = append(parent.keys, last.key)
+		}
+		parent.values[last.key] = replacement
+	case []interface{}:
+		if last.key == "" && last.index >= 0 && last.index < len(parent) {
+			parent[last.index] = replacement
+		}
+	}
+}
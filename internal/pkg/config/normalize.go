@@ -0,0 +1,127 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+)
+
+// legacyKey is a deprecated nested key and the top-level key replacing it
+type legacyKey struct {
+	path     string
+	replaced string
+	nested   func(c *Config) interface{}
+	current  func(c *Config) interface{}
+	set      func(c *Config, value interface{})
+}
+
+// legacyKeys lists the keys of the deprecated youtube and sponsorblock
+// sections
+var legacyKeys = []legacyKey{
+	{
+		path:     "youtube.apikey",
+		replaced: "apikey",
+		nested:   func(c *Config) interface{} { return c.YouTube.APIKey },
+		current:  func(c *Config) interface{} { return c.APIKey },
+		set:      func(c *Config, value interface{}) { c.APIKey = value.(string) },
+	},
+	{
+		path:     "sponsorblock.categories",
+		replaced: "skip_categories",
+		nested:   func(c *Config) interface{} { return c.SponsorBlock.Categories },
+		current:  func(c *Config) interface{} { return c.SkipCategories },
+		set:      func(c *Config, value interface{}) { c.SkipCategories = value.([]string) },
+	},
+	{
+		path:     "sponsorblock.skip_count_tracking",
+		replaced: "skip_count_tracking",
+		nested:   func(c *Config) interface{} { return c.SponsorBlock.SkipCountTracking },
+		current:  func(c *Config) interface{} { return c.SkipCountTracking },
+		set:      func(c *Config, value interface{}) { c.SkipCountTracking = value.(bool) },
+	},
+}
+
+// Normalize merges the deprecated youtube and sponsorblock sections into the
+// top-level keys and removes them, so the rest of the program only reads the
+// top-level keys. A top-level key that is set wins over its nested
+// counterpart; a nested key is only used when the top-level one is unset.
+// A warning is returned for every deprecated key in use.
+//
+// Whether a key is set is taken from the sources of a loaded config, or from
+// the value being non-zero for configs built in code.
+func (c *Config) Normalize() Problems {
+	v := &validator{}
+
+	if c.YouTube == nil {
+		c.YouTube = &types.YouTubeConfig{}
+	}
+	if c.SponsorBlock == nil {
+		c.SponsorBlock = &types.SponsorBlockConfig{}
+	}
+
+	for _, key := range legacyKeys {
+		nested := key.nested(c)
+		if !c.isSet(key.path, nested) {
+			continue
+		}
+
+		current := key.current(c)
+		switch {
+		case !c.isSet(key.replaced, current):
+			key.set(c, nested)
+			c.moveSources(key.path, key.replaced)
+			v.warnf(key.path, "rename it to "+key.replaced, "deprecated key, using its value for %s", key.replaced)
+		case !reflect.DeepEqual(nested, current):
+			v.warnf(key.path, "remove it and keep "+key.replaced, "deprecated key conflicts with %s, which is used instead", key.replaced)
+		default:
+			v.warnf(key.path, "remove it", "deprecated key duplicates %s", key.replaced)
+		}
+	}
+
+	c.YouTube = nil
+	c.SponsorBlock = nil
+	for path := range c.sources {
+		if isBelow(path, "youtube") || isBelow(path, "sponsorblock") {
+			delete(c.sources, path)
+		}
+	}
+
+	return v.problems
+}
+
+// moveSources records the sources of the values below from for the same
+// values below to
+func (c *Config) moveSources(from, to string) {
+	moved := make(Sources)
+	for path, source := range c.sources {
+		if isBelow(path, from) {
+			moved[to+strings.TrimPrefix(path, from)] = source
+		}
+		if isBelow(path, to) {
+			delete(c.sources, path)
+		}
+	}
+	for path, source := range moved {
+		c.sources[path] = source
+	}
+}
+
+// isSet reports whether the value at path was set by the config file or the
+// environment. Without sources, non-zero values count as set.
+func (c *Config) isSet(path string, value interface{}) bool {
+	if c.sources == nil {
+		return !reflect.ValueOf(value).IsZero()
+	}
+	for existing := range c.sources {
+		if isBelow(existing, path) || isBelow(path, existing) && strings.HasPrefix(c.sources[existing], "env ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isBelow reports whether path is parent or one of its children
+func isBelow(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+)
+
+const (
+	// EnvConfigFile overrides the default config file location
+	EnvConfigFile = "SBTV_CONFIG_FILE"
+
+	// EnvDataDir overrides the default data directory
+	EnvDataDir = "SBTV_DATA_DIR"
+)
+
+// ResolveConfigFile returns the config file to use. An explicit path wins,
+// then $SBTV_CONFIG_FILE, then the first of config.json, config.yaml,
+// config.yml and config.toml found in the XDG config directory. Such a file
+// in the working directory is still used when the XDG directory has none.
+// With no config file at all, config.json in the XDG directory is returned.
+func ResolveConfigFile(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv(EnvConfigFile); env != "" {
+		return env, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	appDir := filepath.Join(configDir, constants.AppDirName)
+
+	for _, dir := range []string{appDir, "."} {
+		for _, name := range configFileNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+
+	return filepath.Join(appDir, configFileNames[0]), nil
+}
+
+// ResolveDataDir returns the directory for persistent state. An explicit path
+// wins, then $SBTV_DATA_DIR, then the XDG state directory.
+func ResolveDataDir(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv(EnvDataDir); env != "" {
+		return env, nil
+	}
+
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find data directory: %w", err)
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(stateDir, constants.AppDirName), nil
+}
+
+// DataPath returns the path of a file in the data directory
+func (c *Config) DataPath(name string) string {
+	return filepath.Join(c.DataDir, name)
+}
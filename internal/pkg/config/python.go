@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+)
+
+// ImportReport describes the outcome of ImportPython
+type ImportReport struct {
+	// LoungeTokens holds the lounge tokens of imported devices by screen ID.
+	// They are persistent state and belong in the data directory.
+	LoungeTokens map[string]string
+	// Unmapped lists the values that have no equivalent in Config, as
+	// "path: reason"
+	Unmapped []string
+}
+
+// pythonDevice is a device entry of the Python iSponsorBlockTV config
+type pythonDevice struct {
+	ScreenID    string  `json:"screen_id"`
+	Name        string  `json:"name"`
+	Offset      float64 `json:"offset"`
+	LoungeToken string  `json:"lounge_token"`
+}
+
+// ImportPython converts a config.json of the Python iSponsorBlockTV into a
+// Config. Values that cannot be mapped are listed in the report rather than
+// failing the import.
+func ImportPython(data []byte) (*Config, *ImportReport, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Python config: %w", err)
+	}
+
+	cfg := &Config{}
+	report := &ImportReport{LoungeTokens: make(map[string]string)}
+	unmapped := func(path, format string, args ...interface{}) {
+		report.Unmapped = append(report.Unmapped, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	for _, key := range sortedKeys(raw) {
+		value := raw[key]
+		var err error
+		switch key {
+		case "apikey":
+			err = json.Unmarshal(value, &cfg.APIKey)
+		case "skip_count_tracking":
+			err = json.Unmarshal(value, &cfg.SkipCountTracking)
+		case "mute_ads":
+			err = json.Unmarshal(value, &cfg.MuteAds)
+		case "skip_ads":
+			err = json.Unmarshal(value, &cfg.SkipAds)
+		case "auto_play":
+			err = json.Unmarshal(value, &cfg.AutoPlay)
+		case "join_name":
+			err = json.Unmarshal(value, &cfg.JoinName)
+		case "skip_categories":
+			var categories []string
+			if err = json.Unmarshal(value, &categories); err == nil {
+				for i, category := range categories {
+					if _, ok := constants.GetSkipCategoryByID(category); !ok {
+						unmapped(fmt.Sprintf("skip_categories[%d]", i), "unknown category %q", category)
+						continue
+					}
+					if !constants.IsSkippable(category) {
+						unmapped(fmt.Sprintf("skip_categories[%d]", i), "category %q cannot be skipped", category)
+						continue
+					}
+					cfg.SkipCategories = append(cfg.SkipCategories, category)
+				}
+			}
+		case "minimum_skip_length":
+			var length float64
+			if err = json.Unmarshal(value, &length); err == nil && length > 0 {
+				cfg.SegmentOptions = map[string]SegmentOptions{
+					DefaultSegmentOptions: {MinDuration: length},
+				}
+			}
+		case "channel_whitelist":
+			var channels []types.ChannelInfo
+			if err = json.Unmarshal(value, &channels); err == nil {
+				for i, channel := range channels {
+					if channel.ID == "" {
+						unmapped(fmt.Sprintf("channel_whitelist[%d]", i), "channel ID is empty")
+						continue
+					}
+					cfg.ChannelWhitelist = append(cfg.ChannelWhitelist, channel)
+				}
+			}
+		case "devices":
+			err = importPythonDevices(value, cfg, report, unmapped)
+		default:
+			unmapped(key, "no equivalent setting")
+		}
+
+		if err != nil {
+			unmapped(key, "unexpected value: %v", err)
+		}
+	}
+
+	return cfg, report, nil
+}
+
+// importPythonDevices maps the Python device entries onto cfg.Devices
+func importPythonDevices(value json.RawMessage, cfg *Config, report *ImportReport, unmapped func(path, format string, args ...interface{})) error {
+	var rawDevices []map[string]json.RawMessage
+	if err := json.Unmarshal(value, &rawDevices); err != nil {
+		return err
+	}
+
+	for i, rawDevice := range rawDevices {
+		path := fmt.Sprintf("devices[%d]", i)
+
+		data, _ := json.Marshal(rawDevice)
+		var device pythonDevice
+		if err := json.Unmarshal(data, &device); err != nil {
+			unmapped(path, "unexpected value: %v", err)
+			continue
+		}
+		if device.ScreenID == "" {
+			unmapped(path, "screen ID is empty")
+			continue
+		}
+
+		for _, key := range sortedKeys(rawDevice) {
+			switch key {
+			case "screen_id", "name", "offset", "lounge_token":
+			default:
+				unmapped(path+"."+key, "no equivalent setting")
+			}
+		}
+
+		// The Python version stores offsets in milliseconds
+		cfg.Devices = append(cfg.Devices, DeviceConfig{
+			Name:     device.Name,
+			Offset:   device.Offset / 1000,
+			ScreenID: device.ScreenID,
+		})
+		if device.LoungeToken != "" {
+			report.LoungeTokens[device.ScreenID] = device.LoungeToken
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportPythonSkipsUnskippableCategories(t *testing.T) {
+	data := []byte(`{
+		"devices": [{"screen_id": "a", "name": "TV", "offset": 500, "lounge_token": "token"}],
+		"apikey": "",
+		"skip_categories": ["sponsor", "poi_highlight", "exclusive_access", "selfpromo"],
+		"channel_whitelist": [],
+		"skip_count_tracking": true,
+		"mute_ads": false,
+		"skip_ads": false,
+		"auto_play": true,
+		"join_name": "iSponsorBlockTV"
+	}`)
+
+	cfg, report, err := ImportPython(data)
+	if err != nil {
+		t.Fatalf("ImportPython: %v", err)
+	}
+
+	if want := []string{"sponsor", "selfpromo"}; !reflect.DeepEqual(cfg.SkipCategories, want) {
+		t.Errorf("skip_categories = %q, want %q", cfg.SkipCategories, want)
+	}
+	want := []string{
+		`skip_categories[1]: category "poi_highlight" cannot be skipped`,
+		`skip_categories[2]: category "exclusive_access" cannot be skipped`,
+	}
+	if !reflect.DeepEqual(report.Unmapped, want) {
+		t.Errorf("unmapped = %q, want %q", report.Unmapped, want)
+	}
+
+	if problems := Validate(cfg); problems.HasErrors() {
+		t.Errorf("imported config does not validate: %v", problems)
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/fsutil"
+)
+
+// backupSuffix is appended to the config file name for the previous version
+const backupSuffix = ".bak"
+
+// Save writes the config to cfg.ConfigFile in the format of its extension.
+// The file is replaced atomically and keeps its permissions; the previous
+// version is kept next to it with a .bak suffix. Comments and key order of a
+// YAML or TOML file are preserved. Keys in constants.ConfigFileBlacklistKeys
+// are not written, and values overridden from the environment are written
+// as they were in the file, so secrets passed in the environment never end
+// up on disk.
+func Save(cfg *Config) error {
+	if cfg.ConfigFile == "" {
+		return fmt.Errorf("no config file set")
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	data, err = cfg.restoreFileValues(data)
+	if err != nil {
+		return err
+	}
+	data, err = dropKeys(data, constants.ConfigFileBlacklistKeys)
+	if err != nil {
+		return err
+	}
+
+	format := FormatFromPath(cfg.ConfigFile)
+	l := newLayout()
+	if previous, err := os.ReadFile(cfg.ConfigFile); err == nil {
+		l = layoutOf(previous, format)
+	}
+
+	out, err := fromJSON(data, format, l)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(cfg.ConfigFile, out)
+}
+
+// restoreFileValues replaces the values set from the environment in data, the
+// config as JSON, with the values of the config file. Values the file does not
+// have are left out.
+func (c *Config) restoreFileValues(data []byte) ([]byte, error) {
+	var envPaths []string
+	for path, source := range c.sources {
+		if strings.HasPrefix(source, "env ") {
+			envPaths = append(envPaths, path)
+		}
+	}
+	if len(envPaths) == 0 {
+		return data, nil
+	}
+	// Restore parents before their children
+	sort.Strings(envPaths)
+
+	fileValues := c.fileValues
+	if fileValues == nil {
+		fileValues = []byte("{}")
+	}
+	file, err := decodeOrdered(fileValues)
+	if err != nil {
+		return nil, err
+	}
+	current, err := decodeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range envPaths {
+		steps := parsePath(path)
+		if value, ok := lookupJSON(file, steps); ok {
+			setJSON(current, steps, value)
+			continue
+		}
+		// Remove the outermost value the file does not have, such as a
+		// device added from the environment
+		for n := 1; n <= len(steps); n++ {
+			if _, ok := lookupJSON(file, steps[:n]); !ok {
+				setJSON(current, steps[:n], removedValue{})
+				break
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := encodeOrdered(&out, current); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, backing up the previous contents first
+func writeFileAtomic(path string, data []byte) error {
+	perm := fs.FileMode(0o600)
+	previous, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	if previous != nil {
+		if err := fsutil.ReplaceFile(path+backupSuffix, previous, perm); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	if err := fsutil.ReplaceFile(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// dropKeys removes the given keys from a JSON object, keeping the order of
+// the remaining keys
+func dropKeys(data []byte, keys []string) ([]byte, error) {
+	drop := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		drop[key] = true
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("config is not a JSON object")
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	first := true
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if drop[key] {
+			continue
+		}
+
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+
+		encodedKey, _ := json.Marshal(key)
+		out.Write(encodedKey)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+
+	return out.Bytes(), nil
+}
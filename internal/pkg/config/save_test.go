@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes a JSON config file to a temporary directory and returns
+// its path and the data directory next to it
+func writeConfig(t *testing.T, content string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file, filepath.Join(dir, "data")
+}
+
+// readSaved reads a saved JSON config file into a generic map
+func readSaved(t *testing.T, file string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved config is not JSON: %v\n%s", err, data)
+	}
+	return saved
+}
+
+func TestSaveKeepsEnvOverridesOutOfFile(t *testing.T) {
+	file, dataDir := writeConfig(t, `{
+		"apikey": "FILEKEY",
+		"skip_categories": ["sponsor"],
+		"devices": [{"screen_id": "one", "name": "TV", "offset": 0}]
+	}`)
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("FILESECRET\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SBTV_APIKEY", "SECRET123")
+	t.Setenv("SBTV_JOIN_NAME_FILE", secretFile)
+	t.Setenv("SBTV_DEVICES_0_NAME", "Env TV")
+	t.Setenv("SBTV_DEVICES_1_SCREEN_ID", "two")
+
+	cfg, err := LoadConfig(file, dataDir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.APIKey != "SECRET123" || cfg.JoinName != "FILESECRET" || len(cfg.Devices) != 2 || cfg.Devices[0].Name != "Env TV" {
+		t.Fatalf("overrides not applied: apikey %q, join_name %q, devices %+v", cfg.APIKey, cfg.JoinName, cfg.Devices)
+	}
+
+	cfg.SkipCategories = append(cfg.SkipCategories, "intro")
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	saved := readSaved(t, file)
+	if saved["apikey"] != "FILEKEY" {
+		t.Errorf("apikey = %v, want the file value", saved["apikey"])
+	}
+	if _, ok := saved["join_name"]; ok && saved["join_name"] != "" {
+		t.Errorf("join_name = %v, want it left out", saved["join_name"])
+	}
+	devices, _ := saved["devices"].([]interface{})
+	if len(devices) != 1 {
+		t.Fatalf("devices = %v, want only the file device", saved["devices"])
+	}
+	if name := devices[0].(map[string]interface{})["name"]; name != "TV" {
+		t.Errorf("devices[0].name = %v, want the file value", name)
+	}
+	if want := []interface{}{"sponsor", "intro"}; !reflect.DeepEqual(saved["skip_categories"], want) {
+		t.Errorf("skip_categories = %v, want %v", saved["skip_categories"], want)
+	}
+}
+
+func TestUnknownEnvVariableIsAWarning(t *testing.T) {
+	file, dataDir := writeConfig(t, `{"devices": [{"screen_id": "one"}], "skip_categories": ["sponsor"]}`)
+	t.Setenv("SBTV_NOT_A_KEY", "1")
+
+	cfg, err := LoadConfig(file, dataDir)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	problems := Validate(cfg)
+	if problems.HasErrors() {
+		t.Errorf("unexpected errors: %v", problems)
+	}
+	found := false
+	for _, problem := range problems {
+		if problem.Path == "SBTV_NOT_A_KEY" && problem.Severity == SeverityWarning {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no warning for SBTV_NOT_A_KEY in %v", problems)
+	}
+}
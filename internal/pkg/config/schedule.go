@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule applies a set of overrides to some devices during a recurring
+// window of local time
+type Schedule struct {
+	Name string `json:"name"`
+	// Devices lists the screen IDs or names the schedule applies to, all
+	// devices if empty
+	Devices []string `json:"devices,omitempty"`
+	// Days lists the days the window starts on ("mon".."sun", "weekdays",
+	// "weekends"), every day if empty
+	Days []string `json:"days,omitempty"`
+	// Start and End are local wall-clock times in "15:04" format. An empty
+	// Start means midnight and an empty End means the end of the day. A window
+	// whose End is before its Start runs past midnight into the next day.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	// Profile names an entry of Config.Profiles applied before the schedule's
+	// own overrides
+	Profile string `json:"profile,omitempty"`
+	Overrides
+}
+
+// dayNames maps accepted day names to the days they cover
+var dayNames = map[string][]time.Weekday{
+	"sun":      {time.Sunday},
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// Validate checks that the schedule's days and times can be parsed
+func (s Schedule) Validate() error {
+	if _, err := s.days(); err != nil {
+		return err
+	}
+	if _, _, err := s.window(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppliesTo reports whether the schedule applies to the given device
+func (s Schedule) AppliesTo(device DeviceConfig) bool {
+	if len(s.Devices) == 0 {
+		return true
+	}
+	for _, d := range s.Devices {
+		if d == device.ScreenID || (d != "" && d == device.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAt reports whether the schedule is active at t, evaluated on the wall
+// clock of t's location. Comparing wall-clock minutes rather than durations
+// keeps windows correct across DST changes. Invalid schedules are never active.
+func (s Schedule) ActiveAt(t time.Time) bool {
+	days, err := s.days()
+	if err != nil {
+		return false
+	}
+	start, end, err := s.window()
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	if start < end {
+		return days[today] && start <= minute && minute < end
+	}
+
+	// The window runs past midnight, so its tail belongs to the previous day
+	return (days[today] && minute >= start) || (days[yesterday] && minute < end)
+}
+
+// days returns the days the schedule's window starts on
+func (s Schedule) days() ([7]bool, error) {
+	var days [7]bool
+	if len(s.Days) == 0 {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, name := range s.Days {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if len(key) > 3 && key != "weekdays" && key != "weekends" {
+			key = key[:3]
+		}
+		weekdays, ok := dayNames[key]
+		if !ok {
+			return days, fmt.Errorf("schedule %q: unknown day %q", s.Name, name)
+		}
+		for _, d := range weekdays {
+			days[d] = true
+		}
+	}
+	return days, nil
+}
+
+// window returns the start and end of the schedule in minutes since midnight
+func (s Schedule) window() (int, int, error) {
+	start, end := 0, 24*60
+	var err error
+
+	if s.Start != "" {
+		if start, err = parseClock(s.Start); err != nil {
+			return 0, 0, fmt.Errorf("schedule %q: invalid start: %w", s.Name, err)
+		}
+	}
+	if s.End != "" {
+		if end, err = parseClock(s.End); err != nil {
+			return 0, 0, fmt.Errorf("schedule %q: invalid end: %w", s.Name, err)
+		}
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("schedule %q: start and end are equal", s.Name)
+	}
+
+	return start, end, nil
+}
+
+// parseClock parses a "15:04" wall-clock time into minutes since midnight.
+// "24:00" is accepted as the end of the day.
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("%q is not in HH:MM format", value)
+	}
+
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", value)
+	}
+
+	if h == 24 && m == 0 {
+		return 24 * 60, nil
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("%q is out of range", value)
+	}
+
+	return h*60 + m, nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Setting is a single effective config value
+type Setting struct {
+	// Path is the JSON path of the value, e.g. devices[0].screen_id
+	Path string
+	// Value is the JSON encoding of the value
+	Value string
+	// Source describes where the value came from
+	Source string
+}
+
+// sourceDefault is the source of values set by neither the file nor the
+// environment
+const sourceDefault = "default"
+
+// Settings returns every value of the config with its source, in field
+// order. Unset lists, maps and overrides are left out, as are the config file
+// and data directory locations.
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, 0)
+	for _, field := range jsonFields(reflect.ValueOf(c).Elem()) {
+		if field.name == "config_file" || field.name == "data_dir" {
+			continue
+		}
+		settings = c.appendSettings(settings, field.value, field.name)
+	}
+	return settings
+}
+
+// appendSettings appends the leaf values below v
+func (c *Config) appendSettings(settings []Setting, v reflect.Value, path string) []Setting {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return settings
+		}
+		return c.appendSettings(settings, v.Elem(), path)
+
+	case reflect.Struct:
+		for _, field := range jsonFields(v) {
+			settings = c.appendSettings(settings, field.value, joinPath(path, field.name))
+		}
+		return settings
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return settings
+		}
+		if v.Len() == 0 {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			settings = c.appendSettings(settings, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+		return settings
+
+	case reflect.Map:
+		if v.IsNil() {
+			return settings
+		}
+		if v.Len() == 0 {
+			break
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			settings = c.appendSettings(settings, v.MapIndex(reflect.ValueOf(key)), joinPath(path, key))
+		}
+		return settings
+	}
+
+	value, _ := json.Marshal(v.Interface())
+	return append(settings, Setting{Path: path, Value: string(value), Source: c.sourceOf(path)})
+}
+
+// sourceOf returns the source of the value at path. Values below a parent
+// that was set as a whole from the environment inherit its source.
+func (c *Config) sourceOf(path string) string {
+	if source, ok := c.sources[path]; ok {
+		return source
+	}
+	for {
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return sourceDefault
+		}
+		path = path[:i]
+		if source, ok := c.sources[path]; ok && strings.HasPrefix(source, "env ") {
+			return source
+		}
+	}
+}
+
+// fileSources records every leaf of the JSON document data as coming from file
+func fileSources(sources Sources, data []byte, file string) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return
+	}
+
+	source := "file " + file
+	var walk func(value interface{}, path string)
+	walk = func(value interface{}, path string) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, item := range value {
+				walk(item, joinPath(path, key))
+			}
+		case []interface{}:
+			for i, item := range value {
+				walk(item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		if path != "" {
+			sources[path] = source
+		}
+	}
+	walk(raw, "")
+}
@@ -0,0 +1,325 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// tomlToJSON converts a TOML config document to JSON
+func tomlToJSON(data []byte) ([]byte, error) {
+	value := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// tomlLayout returns the comments and key order of a TOML document, read
+// from the syntax tree of the TOML parser. Documents that cannot be parsed
+// have no layout.
+func tomlLayout(data []byte) *layout {
+	l := newLayout()
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	p := unstable.Parser{KeepComments: true}
+	p.Reset(data)
+
+	table := ""
+	arrayIndex := make(map[string]int)
+	var pending []string
+	pendingLine := 0
+	seenKey := false
+
+	for p.NextExpression() {
+		expr := p.Expression()
+		var line int
+		if expr.Kind == unstable.Comment {
+			line = p.Shape(expr.Raw).Start.Line
+		} else {
+			keys := expr.Key()
+			keys.Next()
+			line = p.Shape(keys.Node().Raw).Start.Line
+		}
+
+		// A blank line after the leading comments ends the document comment
+		if !seenKey && len(pending) > 0 && line > pendingLine+1 {
+			l.head[""] = strings.Join(pending, "\n")
+			pending = nil
+		}
+
+		var path string
+		switch expr.Kind {
+		case unstable.Comment:
+			pending = append(pending, commentLines(string(expr.Data))...)
+			pendingLine = line
+			continue
+
+		case unstable.Table, unstable.ArrayTable:
+			segments := tomlKeySegments(expr.Key())
+			parent := ""
+			for i, segment := range segments {
+				parent = path
+				path = joinPath(path, segment)
+				if i == len(segments)-1 && expr.Kind == unstable.ArrayTable {
+					if _, ok := arrayIndex[path]; ok {
+						arrayIndex[path]++
+					} else {
+						arrayIndex[path] = 0
+					}
+				}
+				l.addKey(parent, segment)
+				if index, ok := arrayIndex[path]; ok {
+					path = fmt.Sprintf("%s[%d]", path, index)
+				}
+			}
+			table = path
+
+		case unstable.KeyValue:
+			path = table
+			for _, segment := range tomlKeySegments(expr.Key()) {
+				l.addKey(path, segment)
+				path = joinPath(path, segment)
+			}
+			tomlValueLayout(l, path, expr.Value())
+		}
+
+		if len(pending) > 0 {
+			l.head[path] = strings.Join(pending, "\n")
+			pending = nil
+		}
+		// The comment at the end of the line follows the expression
+		if comment := expr.Next(); comment != nil && comment.Kind == unstable.Comment {
+			l.line[path] = strings.Join(commentLines(string(comment.Data)), " ")
+		}
+		seenKey = true
+	}
+
+	return l
+}
+
+// tomlKeySegments returns the unquoted segments of a dotted key
+func tomlKeySegments(keys unstable.Iterator) []string {
+	var segments []string
+	for keys.Next() {
+		segments = append(segments, string(keys.Node().Data))
+	}
+	return segments
+}
+
+// tomlValueLayout records the key order of the inline tables in a value
+func tomlValueLayout(l *layout, path string, value *unstable.Node) {
+	switch value.Kind {
+	case unstable.InlineTable:
+		children := value.Children()
+		for children.Next() {
+			keyValue := children.Node()
+			if keyValue.Kind != unstable.KeyValue {
+				continue
+			}
+			keyPath := path
+			for _, segment := range tomlKeySegments(keyValue.Key()) {
+				l.addKey(keyPath, segment)
+				keyPath = joinPath(keyPath, segment)
+			}
+			tomlValueLayout(l, keyPath, keyValue.Value())
+		}
+
+	case unstable.Array:
+		index := 0
+		children := value.Children()
+		for children.Next() {
+			item := children.Node()
+			if item.Kind == unstable.Comment {
+				continue
+			}
+			tomlValueLayout(l, fmt.Sprintf("%s[%d]", path, index), item)
+			index++
+		}
+	}
+}
+
+// encodeTOML writes the tree as TOML with the comments of the layout
+func encodeTOML(root *object, l *layout) ([]byte, error) {
+	var out bytes.Buffer
+	if comment := l.head[""]; comment != "" {
+		writeTOMLComment(&out, comment)
+		out.WriteString("\n")
+	}
+	if err := writeTOMLTable(&out, root, "", nil, l); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// writeTOMLTable writes the keys of a table followed by its sub-tables.
+// path is the JSON path of the table and header its TOML key segments.
+func writeTOMLTable(out *bytes.Buffer, table *object, path string, header []string, l *layout) error {
+	// Plain values must come before any sub-table
+	for _, key := range table.keys {
+		value := table.values[key]
+		if value == nil || isTOMLTable(value) || isTOMLTableArray(value) {
+			continue
+		}
+		keyPath := joinPath(path, key)
+		writeTOMLComment(out, l.head[keyPath])
+
+		encoded, err := encodeTOMLKeyValue(key, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", keyPath, err)
+		}
+		out.WriteString(encoded)
+		if comment := l.line[keyPath]; comment != "" {
+			out.WriteString(" # " + comment)
+		}
+		out.WriteString("\n")
+	}
+
+	for _, key := range table.keys {
+		value := table.values[key]
+		keyPath := joinPath(path, key)
+		keyHeader := append(append([]string(nil), header...), key)
+
+		switch {
+		case isTOMLTable(value):
+			// Tables holding only sub-tables are implied by their headers
+			sub := value.(*object)
+			if !tomlImplicitTable(sub) || l.head[keyPath] != "" || l.line[keyPath] != "" {
+				out.WriteString("\n")
+				writeTOMLComment(out, l.head[keyPath])
+				out.WriteString("[" + tomlHeader(keyHeader) + "]")
+				if comment := l.line[keyPath]; comment != "" {
+					out.WriteString(" # " + comment)
+				}
+				out.WriteString("\n")
+			}
+			if err := writeTOMLTable(out, sub, keyPath, keyHeader, l); err != nil {
+				return err
+			}
+
+		case isTOMLTableArray(value):
+			for i, item := range value.([]interface{}) {
+				itemPath := fmt.Sprintf("%s[%d]", keyPath, i)
+				out.WriteString("\n")
+				if i == 0 {
+					writeTOMLComment(out, l.head[keyPath])
+				}
+				writeTOMLComment(out, l.head[itemPath])
+				out.WriteString("[[" + tomlHeader(keyHeader) + "]]")
+				if comment := l.line[itemPath]; comment != "" {
+					out.WriteString(" # " + comment)
+				}
+				out.WriteString("\n")
+				if err := writeTOMLTable(out, item.(*object), itemPath, keyHeader, l); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isTOMLTable reports whether value is written as a table
+func isTOMLTable(value interface{}) bool {
+	_, ok := value.(*object)
+	return ok
+}
+
+// tomlImplicitTable reports whether a table has sub-tables and nothing else
+func tomlImplicitTable(table *object) bool {
+	if len(table.keys) == 0 {
+		return false
+	}
+	for _, key := range table.keys {
+		if !isTOMLTable(table.values[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTOMLTableArray reports whether value is written as an array of tables
+func isTOMLTableArray(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if _, ok := item.(*object); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeTOMLKeyValue encodes a key and its plain value on one line
+func encodeTOMLKeyValue(key string, value interface{}) (string, error) {
+	encoded, err := toml.Marshal(map[string]interface{}{key: tomlValue(value)})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
+
+// tomlValue converts a tree value to the values the TOML encoder takes
+func tomlValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case []interface{}:
+		items := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if item != nil {
+				items = append(items, tomlValue(item))
+			}
+		}
+		return items
+	case *object:
+		table := make(map[string]interface{}, len(value.keys))
+		for _, key := range value.keys {
+			if value.values[key] != nil {
+				table[key] = tomlValue(value.values[key])
+			}
+		}
+		return table
+	}
+	return value
+}
+
+// tomlKey quotes a key the way the TOML encoder does
+func tomlKey(key string) string {
+	encoded, _ := toml.Marshal(map[string]bool{key: true})
+	return strings.TrimSuffix(string(encoded), " = true\n")
+}
+
+// tomlHeader joins key segments into a table header
+func tomlHeader(segments []string) string {
+	keys := make([]string, len(segments))
+	for i, segment := range segments {
+		keys[i] = tomlKey(segment)
+	}
+	return strings.Join(keys, ".")
+}
+
+// writeTOMLComment writes comment lines
+func writeTOMLComment(out *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if line == "" {
+			out.WriteString("#\n")
+		} else {
+			out.WriteString("# " + line + "\n")
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTOMLLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		head  map[string]string
+		line  map[string]string
+		order map[string][]string
+	}{
+		{
+			name: "comments",
+			doc: "# go-SponsorBlockTV\r\n" +
+				"#\r\n" +
+				"# See the README\r\n" +
+				"\r\n" +
+				"# Shown on the TV\r\n" +
+				"join_name = \"Skipper\" # a # in a comment\r\n" +
+				"api_key = \"a#b\"\r\n",
+			head:  map[string]string{"": "go-SponsorBlockTV\n\nSee the README", "join_name": "Shown on the TV"},
+			line:  map[string]string{"join_name": "a # in a comment"},
+			order: map[string][]string{"": {"join_name", "api_key"}},
+		},
+		{
+			name: "multiline arrays",
+			doc: "# Categories\n" +
+				"skip_categories = [\n" +
+				"  \"sponsor\", # paid\n" +
+				"  \"intro\",\n" +
+				"] # skipped\n" +
+				"mute_ads = true\n",
+			head:  map[string]string{"skip_categories": "Categories"},
+			line:  map[string]string{"skip_categories": "skipped"},
+			order: map[string][]string{"": {"skip_categories", "mute_ads"}},
+		},
+		{
+			name: "inline tables",
+			doc: "segment_options = { sponsor = { min_duration = 5, padding = 1 }, intro = { padding = 2 } }\n" +
+				"devices = [{ screen_id = \"a\", name = \"TV\" }]\n",
+			head: map[string]string{},
+			line: map[string]string{},
+			order: map[string][]string{
+				"":                        {"segment_options", "devices"},
+				"segment_options":         {"sponsor", "intro"},
+				"segment_options.sponsor": {"min_duration", "padding"},
+				"devices[0]":              {"screen_id", "name"},
+			},
+		},
+		{
+			name: "quoted keys",
+			doc: "\"with space\" = 1\n" +
+				"'literal.key' = 2\n" +
+				"\n" +
+				"# At night\n" +
+				"[profiles.\"late night\"] # quiet\n" +
+				"mute_ads = true\n",
+			head: map[string]string{"profiles.late night": "At night"},
+			line: map[string]string{"profiles.late night": "quiet"},
+			order: map[string][]string{
+				"":                    {"with space", "literal.key", "profiles"},
+				"profiles":            {"late night"},
+				"profiles.late night": {"mute_ads"},
+			},
+		},
+		{
+			name: "array tables",
+			doc: "# First device\n" +
+				"[[devices]]\n" +
+				"screen_id = \"a\"\n" +
+				"name = \"TV\"\n" +
+				"\n" +
+				"[[devices]] # second\n" +
+				"name = \"Other\"\n" +
+				"screen_id = \"b\"\n",
+			head: map[string]string{"devices[0]": "First device"},
+			line: map[string]string{"devices[1]": "second"},
+			order: map[string][]string{
+				"":           {"devices"},
+				"devices[0]": {"screen_id", "name"},
+				"devices[1]": {"name", "screen_id"},
+			},
+		},
+		{
+			name:  "invalid",
+			doc:   "key = [\n",
+			head:  map[string]string{},
+			line:  map[string]string{},
+			order: map[string][]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := tomlLayout([]byte(test.doc))
+			if !reflect.DeepEqual(l.head, test.head) {
+				t.Errorf("head = %q, want %q", l.head, test.head)
+			}
+			if !reflect.DeepEqual(l.line, test.line) {
+				t.Errorf("line = %q, want %q", l.line, test.line)
+			}
+			for path, want := range test.order {
+				if got := l.order[path]; !reflect.DeepEqual(got, want) {
+					t.Errorf("order[%q] = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		// want lists lines the output must contain
+		want []string
+	}{
+		{
+			name: "comments and order",
+			doc: "# go-SponsorBlockTV\n" +
+				"\n" +
+				"# Shown on the TV\n" +
+				"join_name = \"Skipper\" # short\n" +
+				"api_key = \"key\"\n",
+			want: []string{"# go-SponsorBlockTV", "# Shown on the TV", "join_name = 'Skipper' # short"},
+		},
+		{
+			name: "multiline arrays",
+			doc: "skip_categories = [\n" +
+				"  \"sponsor\",\n" +
+				"  \"intro\",\n" +
+				"]\n",
+			want: []string{"skip_categories = ['sponsor', 'intro']"},
+		},
+		{
+			name: "inline tables",
+			doc: "offset = 0.5\n" +
+				"segment_options = { sponsor = { min_duration = 5 } }\n" +
+				"mixed = [1, { a = 2 }]\n",
+			want: []string{"offset = 0.5", "[segment_options.sponsor]", "min_duration = 5", "mixed = [1, {a = 2}]"},
+		},
+		{
+			name: "quoted keys",
+			doc: "[profiles.\"late night\"]\n" +
+				"\"mute ads\" = true\n",
+			want: []string{"[profiles.'late night']", "'mute ads' = true"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := toJSON([]byte(test.doc), FormatTOML)
+			if err != nil {
+				t.Fatalf("toJSON: %v", err)
+			}
+			out, err := fromJSON(data, FormatTOML, tomlLayout([]byte(test.doc)))
+			if err != nil {
+				t.Fatalf("fromJSON: %v", err)
+			}
+
+			lines := strings.Split(string(out), "\n")
+			for _, want := range test.want {
+				if !containsString(lines, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+
+			again, err := toJSON(out, FormatTOML)
+			if err != nil {
+				t.Fatalf("output does not parse: %v\n%s", err, out)
+			}
+			var before, after interface{}
+			json.Unmarshal(data, &before)
+			json.Unmarshal(again, &after)
+			if !reflect.DeepEqual(before, after) {
+				t.Errorf("round trip changed the values: %s, want %s", again, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+)
+
+// videoIDPattern matches YouTube video IDs
+var videoIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{11}$`)
+
+// maxOffset is the largest device offset in seconds that is not reported as
+// a mistake
+const maxOffset = 30
+
+// Severity is the severity of a validation problem
+type Severity int
+
+const (
+	// SeverityWarning marks problems the daemon can run with
+	SeverityWarning Severity = iota
+	// SeverityError marks problems the daemon refuses to start with
+	SeverityError
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is a single finding of Validate
+type Problem struct {
+	Severity Severity
+	// Path is the JSON path of the offending value, e.g. devices[0].screen_id
+	Path    string
+	Message string
+	// Hint suggests how to fix the problem
+	Hint string
+}
+
+// String formats the problem on a single line
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s: %s: %s", p.Severity, p.Path, p.Message)
+	if p.Hint != "" {
+		s += " (" + p.Hint + ")"
+	}
+	return s
+}
+
+// Problems is the result of Validate
+type Problems []Problem
+
+// HasErrors reports whether any problem is an error
+func (p Problems) HasErrors() bool {
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validator collects problems
+type validator struct {
+	problems Problems
+}
+
+func (v *validator) errorf(path, hint, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{SeverityError, path, fmt.Sprintf(format, args...), hint})
+}
+
+func (v *validator) warnf(path, hint, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{SeverityWarning, path, fmt.Sprintf(format, args...), hint})
+}
+
+// Validate checks the config for values that would fail or misbehave at
+// runtime and returns every problem found
+func Validate(cfg *Config) Problems {
+	v := &validator{problems: append(Problems(nil), cfg.warnings...)}
+
+	// Devices
+	if len(cfg.Devices) == 0 {
+		v.warnf("devices", "run setup to add a device", "no devices configured")
+	}
+	screenIDs := make(map[string]int)
+	for i, device := range cfg.Devices {
+		path := fmt.Sprintf("devices[%d]", i)
+		if strings.TrimSpace(device.ScreenID) == "" {
+			v.errorf(path+".screen_id", "pair the device with setup or remove the entry", "screen ID is empty")
+		} else if first, ok := screenIDs[device.ScreenID]; ok {
+			v.errorf(path+".screen_id", fmt.Sprintf("remove one of devices[%d] and %s", first, path), "duplicate screen ID %q", device.ScreenID)
+		} else {
+			screenIDs[device.ScreenID] = i
+		}
+		if math.IsNaN(device.Offset) || math.Abs(device.Offset) > maxOffset {
+			v.errorf(path+".offset", "the offset is in seconds; most TVs need less than 2", "offset %v is out of range", device.Offset)
+		}
+		v.validateOverrides(path, device.Overrides)
+	}
+
+	// Categories
+	if len(cfg.SkipCategories) == 0 && !hasAction(cfg.CategoryActions) {
+		v.warnf("skip_categories", "add at least one category, e.g. \"sponsor\"", "no categories selected, nothing will be skipped")
+	}
+	v.validateCategories("skip_categories", cfg.SkipCategories)
+
+	// Whitelist and channel rules need the YouTube API to resolve channels
+	v.validateWhitelist("channel_whitelist", cfg.ChannelWhitelist)
+	v.validateCategoryActions("category_actions", cfg.CategoryActions)
+	v.validateChannelRules("channel_rules", cfg.ChannelRules)
+	if cfg.APIKey == "" {
+		if len(cfg.ChannelWhitelist) > 0 {
+			v.warnf("channel_whitelist", "set apikey to a YouTube Data API key", "channel whitelist is ignored without an API key")
+		}
+		if len(cfg.ChannelRules) > 0 {
+			v.warnf("channel_rules", "set apikey to a YouTube Data API key", "channel rules are ignored without an API key")
+		}
+	}
+
+	v.validateSegmentOptions("segment_options", cfg.SegmentOptions)
+	if cfg.CalibrationVideo != "" && !videoIDPattern.MatchString(cfg.CalibrationVideo) {
+		v.errorf("calibration_video", "use the 11 character ID from the video URL", "invalid video ID %q", cfg.CalibrationVideo)
+	}
+
+	// Profiles and schedules
+	for _, name := range sortedKeys(cfg.Profiles) {
+		v.validateOverrides(fmt.Sprintf("profiles.%s", name), cfg.Profiles[name])
+	}
+	for i, schedule := range cfg.Schedules {
+		path := fmt.Sprintf("schedules[%d]", i)
+		if err := schedule.Validate(); err != nil {
+			v.errorf(path, "times are HH:MM and days are mon..sun, weekdays or weekends", "%v", err)
+		}
+		if schedule.Profile != "" {
+			if _, ok := cfg.Profiles[schedule.Profile]; !ok {
+				v.errorf(path+".profile", "add it under profiles or remove the reference", "unknown profile %q", schedule.Profile)
+			}
+		}
+		for j, ref := range schedule.Devices {
+			if !deviceExists(cfg.Devices, ref) {
+				v.warnf(fmt.Sprintf("%s.devices[%d]", path, j), "use a screen ID or name from devices", "no device matches %q", ref)
+			}
+		}
+		v.validateOverrides(path, schedule.Overrides)
+	}
+
+	if cfg.Discovery != nil {
+		v.validateInterfaces("discovery.interfaces", cfg.Discovery.Interfaces)
+		v.validateInterfaces("discovery.exclude_interfaces", cfg.Discovery.ExcludeInterfaces)
+	}
+
+	return v.problems
+}
+
+// validateOverrides checks the overridable options under path
+func (v *validator) validateOverrides(path string, o Overrides) {
+	v.validateCategories(path+".skip_categories", valueOf(o.SkipCategories))
+	v.validateCategoryActions(path+".category_actions", valueOf(o.CategoryActions))
+	v.validateWhitelist(path+".channel_whitelist", valueOf(o.ChannelWhitelist))
+	v.validateChannelRules(path+".channel_rules", valueOf(o.ChannelRules))
+	v.validateSegmentOptions(path+".segment_options", valueOf(o.SegmentOptions))
+}
+
+// valueOf returns the value p points to, the zero value for nil
+func valueOf[T any](p *T) T {
+	var value T
+	if p != nil {
+		value = *p
+	}
+	return value
+}
+
+// validateCategories checks that every entry is a known category ID
+func (v *validator) validateCategories(path string, categories []string) {
+	seen := make(map[string]bool)
+	for i, id := range categories {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if !v.validateCategory(itemPath, id) {
+			continue
+		}
+		v.validateAction(itemPath, id, constants.ActionSkip)
+		if seen[id] {
+			v.warnf(itemPath, "remove the duplicate", "category %q is listed twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+// validateCategory reports an unknown category ID and whether it was valid
+func (v *validator) validateCategory(path, id string) bool {
+	if _, ok := constants.GetSkipCategoryByID(id); ok {
+		return true
+	}
+
+	hint := "valid categories are " + strings.Join(constants.GetSkipCategoryIDs(), ", ")
+	for _, category := range constants.SkipCategories {
+		if strings.EqualFold(category.Name, id) || strings.EqualFold(category.ID, id) {
+			hint = fmt.Sprintf("use the ID %q instead", category.ID)
+			break
+		}
+	}
+	v.errorf(path, hint, "unknown category %q", id)
+	return false
+}
+
+// validateWhitelist checks that every whitelist entry has a channel ID
+func (v *validator) validateWhitelist(path string, whitelist []types.ChannelInfo) {
+	for i, channel := range whitelist {
+		if strings.TrimSpace(channel.ID) == "" {
+			v.errorf(fmt.Sprintf("%s[%d].id", path, i), "set the channel ID or remove the entry", "channel ID is empty")
+		}
+	}
+}
+
+// validateChannelRules checks the channel IDs, categories and actions of the rules
+func (v *validator) validateChannelRules(path string, rules map[string]ChannelRule) {
+	for _, channelID := range sortedKeys(rules) {
+		rule := rules[channelID]
+		rulePath := fmt.Sprintf("%s.%s", path, channelID)
+		if strings.TrimSpace(channelID) == "" {
+			v.errorf(rulePath, "key the rule by channel ID", "channel ID is empty")
+		}
+		v.validateCategoryActions(rulePath+".categories", rule.Categories)
+	}
+}
+
+// validateCategoryActions checks the categories and actions of a category to
+// action map
+func (v *validator) validateCategoryActions(path string, actions map[string]string) {
+	for _, category := range sortedKeys(actions) {
+		action := actions[category]
+		categoryPath := fmt.Sprintf("%s.%s", path, category)
+		v.validateCategory(categoryPath, category)
+		if !constants.IsSegmentAction(action) {
+			v.errorf(categoryPath, "valid actions are "+strings.Join(constants.SegmentActions, ", "), "unknown action %q", action)
+			continue
+		}
+		v.validateAction(categoryPath, category, action)
+	}
+}
+
+// validateAction reports an action that a category does not support
+func (v *validator) validateAction(path, category, action string) {
+	if action != constants.ActionIgnore && !constants.IsSkippable(category) {
+		v.errorf(path, "SponsorBlock only returns these segments as labels; remove the category", "category %q cannot be skipped or muted", category)
+	}
+}
+
+// hasAction reports whether any category is assigned an action other than
+// ignore
+func hasAction(actions map[string]string) bool {
+	for _, action := range actions {
+		if action != constants.ActionIgnore {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSegmentOptions checks that options are keyed by category and not negative
+func (v *validator) validateSegmentOptions(path string, options map[string]SegmentOptions) {
+	for _, category := range sortedKeys(options) {
+		o := options[category]
+		optionsPath := fmt.Sprintf("%s.%s", path, category)
+		if category != DefaultSegmentOptions {
+			v.validateCategory(optionsPath, category)
+		}
+		values := []struct {
+			name  string
+			value float64
+		}{
+			{"min_duration", o.MinDuration},
+			{"start_padding", o.StartPadding},
+			{"end_padding", o.EndPadding},
+			{"min_remaining", o.MinRemaining},
+		}
+		for _, value := range values {
+			if value.value < 0 || math.IsNaN(value.value) {
+				v.errorf(optionsPath+"."+value.name, "use a number of seconds, 0 to disable", "%v is negative", value.value)
+			}
+		}
+	}
+}
+
+// validateInterfaces checks that every entry is a valid interface name pattern
+func (v *validator) validateInterfaces(listPath string, patterns []string) {
+	for i, pattern := range patterns {
+		entryPath := fmt.Sprintf("%s[%d]", listPath, i)
+		if strings.TrimSpace(pattern) == "" {
+			v.errorf(entryPath, "use an interface name such as \"eth0\" or a pattern such as \"docker*\"", "interface name is empty")
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			v.errorf(entryPath, "patterns use * and ? wildcards and [a-z] ranges", "invalid pattern %q", pattern)
+		}
+	}
+}
+
+// deviceExists reports whether a device has the given screen ID or name
+func deviceExists(devices []DeviceConfig, ref string) bool {
+	for _, device := range devices {
+		if device.ScreenID == ref || (ref != "" && device.Name == ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls the file at path every interval and calls onChange when its
+// modification time or size changes, until ctx is done. Polling also catches
+// editors and Save replacing the file by renaming over it.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	stat := func() (time.Time, int64, bool) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		return info.ModTime(), info.Size(), true
+	}
+
+	lastMod, lastSize, lastOK := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		mod, size, ok := stat()
+		if ok == lastOK && mod.Equal(lastMod) && size == lastSize {
+			continue
+		}
+		lastMod, lastSize, lastOK = mod, size, ok
+
+		// A missing file is usually a save in progress
+		if ok {
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToJSON converts a YAML config document to JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = map[string]interface{}{}
+	}
+	return json.Marshal(value)
+}
+
+// yamlLayout returns the comments and key order of a YAML document. Documents
+// that cannot be parsed have no layout.
+func yamlLayout(data []byte) *layout {
+	l := newLayout()
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return l
+	}
+	if doc.HeadComment != "" {
+		l.head[""] = strings.Join(commentLines(doc.HeadComment), "\n")
+	}
+
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				keyPath := joinPath(path, key.Value)
+				l.addKey(path, key.Value)
+				if key.HeadComment != "" {
+					l.head[keyPath] = strings.Join(commentLines(key.HeadComment), "\n")
+				}
+				if comment := key.LineComment + value.LineComment; comment != "" {
+					l.line[keyPath] = strings.Join(commentLines(comment), " ")
+				}
+				walk(value, keyPath)
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				if item.HeadComment != "" {
+					l.head[itemPath] = strings.Join(commentLines(item.HeadComment), "\n")
+				}
+				if item.Kind == yaml.ScalarNode && item.LineComment != "" {
+					l.line[itemPath] = strings.Join(commentLines(item.LineComment), " ")
+				}
+				walk(item, itemPath)
+			}
+		}
+	}
+	walk(doc.Content[0], "")
+
+	return l
+}
+
+// encodeYAML writes the tree as YAML with the comments of the layout
+func encodeYAML(root *object, l *layout) ([]byte, error) {
+	doc := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: yamlComment(l.head[""]),
+		Content:     []*yaml.Node{yamlNode(root, "", l)},
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// yamlNode converts a tree value to a YAML node
+func yamlNode(value interface{}, path string, l *layout) *yaml.Node {
+	switch value := value.(type) {
+	case *object:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range value.keys {
+			item := value.values[key]
+			if item == nil {
+				continue
+			}
+			keyPath := joinPath(path, key)
+			keyNode := &yaml.Node{
+				Kind:        yaml.ScalarNode,
+				Tag:         "!!str",
+				Value:       key,
+				HeadComment: yamlComment(l.head[keyPath]),
+			}
+			valueNode := yamlNode(item, keyPath, l)
+			if comment := l.line[keyPath]; comment != "" {
+				if valueNode.Kind == yaml.ScalarNode || len(valueNode.Content) == 0 {
+					valueNode.LineComment = "# " + comment
+				} else {
+					keyNode.LineComment = "# " + comment
+				}
+			}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+		return node
+
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i, item := range value {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			itemNode := yamlNode(item, itemPath, l)
+			itemNode.HeadComment = yamlComment(l.head[itemPath])
+			if comment := l.line[itemPath]; comment != "" && itemNode.Kind == yaml.ScalarNode {
+				itemNode.LineComment = "# " + comment
+			}
+			node.Content = append(node.Content, itemNode)
+		}
+		return node
+
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}
+
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}
+
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(value)}
+	}
+}
+
+// yamlComment formats comment lines as a YAML comment
+func yamlComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = "#"
+		} else {
+			lines[i] = "# " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -4,6 +4,9 @@ const (
 	// UserAgent is the user agent string for API requests
 	UserAgent = "go-SponsorBlockTV/0.1"
 
+	// AppDirName is the name of the config and data directories
+	AppDirName = "iSponsorBlockTV"
+
 	// SponsorBlockService is the service name for SponsorBlock
 	SponsorBlockService = "youtube"
 
@@ -40,6 +43,44 @@ var SkipCategories = []SkipCategory{
 	{"Filler", "filler"},
 }
 
+// Segment actions that can be assigned to a skip category
+const (
+	// ActionSkip seeks past the segment
+	ActionSkip = "skip"
+
+	// ActionMute mutes the device for the duration of the segment
+	ActionMute = "mute"
+
+	// ActionIgnore leaves the segment alone
+	ActionIgnore = "ignore"
+)
+
+// SegmentActions is a list of actions that can be assigned to a skip category
+var SegmentActions = []string{ActionSkip, ActionMute, ActionIgnore}
+
+// unskippableCategories lists the categories SponsorBlock never returns for
+// the skip action type: POI highlights mark a single point and exclusive
+// access labels a whole video
+var unskippableCategories = []string{"poi_highlight", "exclusive_access"}
+
+// IsSkippable reports whether segments of a category can be skipped or muted
+func IsSkippable(id string) bool {
+	for _, category := range unskippableCategories {
+		if category == id {
+			return false
+		}
+	}
+	return true
+}
+
+// ActionsFor returns the actions that can be assigned to a category
+func ActionsFor(id string) []string {
+	if !IsSkippable(id) {
+		return []string{ActionIgnore}
+	}
+	return SegmentActions
+}
+
 // YouTubeClientBlacklist is a list of YouTube clients that should be blacklisted
 var YouTubeClientBlacklist = []string{"TVHTML5_FOR_KIDS"}
 
@@ -74,3 +115,13 @@ func GetSkipCategoryByName(name string) (SkipCategory, bool) {
 	}
 	return SkipCategory{}, false
 }
+
+// IsSegmentAction reports whether action is a known segment action
+func IsSegmentAction(action string) bool {
+	for _, a := range SegmentActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
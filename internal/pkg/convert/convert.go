@@ -5,7 +5,8 @@ import (
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
 )
 
-// ToApiConfig converts a config.Config to an api.Config
+// ToApiConfig converts a config.Config to an api.Config. Only the top-level
+// keys are read, so cfg must have been normalised as LoadConfig does.
 func ToApiConfig(cfg *config.Config) *api.Config {
 	apiConfig := &api.Config{
 		APIKey:            cfg.APIKey,
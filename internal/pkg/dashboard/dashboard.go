@@ -0,0 +1,378 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/status"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pollInterval is how often the daemon is asked for its state
+const pollInterval = time.Second
+
+// logLimit is the number of log entries kept for scrolling
+const logLimit = 500
+
+// Model is the dashboard of a running daemon
+type Model struct {
+	client *status.Client
+
+	devices []status.DeviceStatus
+	// updated is when devices was received
+	updated time.Time
+	cursor  int
+
+	log      []status.LogEntry
+	logSince uint64
+	// logScroll is the number of log lines scrolled up from the newest
+	logScroll int
+
+	// err is set while the daemon cannot be reached
+	err error
+	// Message of the last command
+	message    string
+	messageErr bool
+
+	width  int
+	height int
+}
+
+// snapshotMsg carries the state of the daemon
+type snapshotMsg struct {
+	snapshot *status.Snapshot
+	err      error
+}
+
+// pollMsg asks for the next snapshot
+type pollMsg struct{}
+
+// commandMsg carries the result of a command sent to the daemon
+type commandMsg struct {
+	message string
+	err     error
+}
+
+// New creates a dashboard that talks to the daemon over client
+func New(client *status.Client) Model {
+	return Model{client: client}
+}
+
+// Init asks for the first snapshot
+func (m Model) Init() tea.Cmd {
+	return m.poll()
+}
+
+// poll asks the daemon for its state in the background
+func (m Model) poll() tea.Cmd {
+	client, since := m.client, m.logSince
+	return func() tea.Msg {
+		snapshot, err := client.Status(since)
+		return snapshotMsg{snapshot: snapshot, err: err}
+	}
+}
+
+// Update handles messages and updates the model
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.updateKey(msg)
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		styles.Resize(msg.Width)
+	case snapshotMsg:
+		m = m.handleSnapshot(msg)
+		return m, tea.Tick(pollInterval, func(time.Time) tea.Msg { return pollMsg{} })
+	case pollMsg:
+		return m, m.poll()
+	case commandMsg:
+		m.message = msg.message
+		m.messageErr = msg.err != nil
+		if msg.err != nil {
+			m.message = msg.err.Error()
+		}
+	}
+	return m, nil
+}
+
+// handleSnapshot records the state of the daemon and appends new log entries
+func (m Model) handleSnapshot(msg snapshotMsg) Model {
+	m.err = msg.err
+	if msg.err != nil {
+		return m
+	}
+
+	m.devices = msg.snapshot.Devices
+	m.updated = time.Now()
+	if m.cursor >= len(m.devices) {
+		m.cursor = max(len(m.devices)-1, 0)
+	}
+
+	for _, entry := range msg.snapshot.Log {
+		m.log = append(m.log, entry)
+		m.logSince = entry.Seq
+		if m.logScroll > 0 {
+			// Keep the scrolled view in place
+			m.logScroll++
+		}
+	}
+	if len(m.log) > logLimit {
+		m.log = m.log[len(m.log)-logLimit:]
+	}
+	m.logScroll = min(m.logScroll, len(m.log))
+	return m
+}
+
+// updateKey handles a key press
+func (m Model) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.devices)-1 {
+			m.cursor++
+		}
+	case "p":
+		if device, ok := m.selected(); ok {
+			return m, m.setSkippingPaused(device, !device.SkippingPaused)
+		}
+	case "s":
+		if device, ok := m.selected(); ok {
+			return m, m.skip(device)
+		}
+	case "pgup", "K":
+		m.logScroll = min(m.logScroll+m.logHeight(), max(len(m.log)-1, 0))
+	case "pgdown", "J":
+		m.logScroll = max(m.logScroll-m.logHeight(), 0)
+	case "end", "G":
+		m.logScroll = 0
+	}
+	return m, nil
+}
+
+// selected returns the device under the cursor
+func (m Model) selected() (status.DeviceStatus, bool) {
+	if m.cursor < len(m.devices) {
+		return m.devices[m.cursor], true
+	}
+	return status.DeviceStatus{}, false
+}
+
+// setSkippingPaused pauses or resumes skipping on a device in the background
+func (m Model) setSkippingPaused(device status.DeviceStatus, paused bool) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		if err := client.SetSkippingPaused(device.ScreenID, paused); err != nil {
+			return commandMsg{err: err}
+		}
+		if paused {
+			return commandMsg{message: "Skipping paused on " + deviceName(device)}
+		}
+		return commandMsg{message: "Skipping resumed on " + deviceName(device)}
+	}
+}
+
+// skip forces the skip of the next segment of a device in the background
+func (m Model) skip(device status.DeviceStatus) tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		if err := client.Skip(device.ScreenID); err != nil {
+			return commandMsg{err: err}
+		}
+		return commandMsg{message: "Skipping the next segment on " + deviceName(device)}
+	}
+}
+
+// View renders the dashboard
+func (m Model) View() string {
+	header := styles.Title.Copy().Width(m.layoutWidth()).Render("iSponsorBlockTV - Dashboard") + "\n"
+
+	var statusLine string
+	switch {
+	case m.err != nil:
+		statusLine = styles.StatusError.Render("Lost connection to the daemon: "+m.err.Error()) + "\n"
+	case m.message != "" && m.messageErr:
+		statusLine = styles.StatusError.Render(m.message) + "\n"
+	case m.message != "":
+		statusLine = styles.StatusSuccess.Render(m.message) + "\n"
+	}
+
+	footer := styles.Footer.Render(
+		"up/down: Select  p: Pause/resume skipping  s: Skip now  pgup/pgdown: Scroll log  q: Exit",
+	)
+
+	devices := styles.Container.Render(m.renderDevices()) + "\n"
+
+	logHeight := m.logHeight()
+	if m.height > 0 {
+		used := lipgloss.Height(header) + lipgloss.Height(devices) + lipgloss.Height(statusLine) + lipgloss.Height(footer)
+		logHeight = max(m.height-used-styles.Container.GetVerticalFrameSize(), 1)
+	}
+	logPane := styles.Container.Render(m.renderLog(logHeight)) + "\n"
+
+	return header + devices + logPane + statusLine + footer
+}
+
+// layoutWidth is the width the dashboard is drawn in
+func (m Model) layoutWidth() int {
+	if m.width > 0 {
+		return m.width
+	}
+	return 100
+}
+
+// logHeight is the number of log lines scrolled by a page
+func (m Model) logHeight() int {
+	if m.height > 0 {
+		return max(m.height/3, 1)
+	}
+	return 10
+}
+
+// renderDevices renders a row per device
+func (m Model) renderDevices() string {
+	var s strings.Builder
+	s.WriteString(styles.Subtitle.Render("Devices") + "\n")
+	if len(m.devices) == 0 {
+		if m.updated.IsZero() {
+			s.WriteString(styles.SelectionItem.Render("Connecting to the daemon..."))
+		} else {
+			s.WriteString(styles.SelectionItem.Render("The daemon runs no devices"))
+		}
+		return s.String()
+	}
+
+	now := time.Now()
+	width := styles.ContentWidth() - 1
+	s.WriteString(styles.SelectionItem.Render(truncate(fmt.Sprintf("  %-18s %-12s %-11s %-8s %-22s %-7s %-8s %s",
+		"Device", "State", "Now playing", "Position", "Next segment", "Ads", "Skipping", "Schedule"), width)) + "\n")
+	for i, device := range m.devices {
+		cursor := " "
+		style := styles.SelectionItem
+		if i == m.cursor {
+			cursor = ">"
+			style = styles.SelectionItemActive
+		}
+
+		skipping := "on"
+		if device.SkippingPaused {
+			skipping = "paused"
+		}
+		ad := device.Ad
+		if ad == status.AdNone {
+			ad = "-"
+		}
+		state := device.State
+		if device.Network == status.NetworkOffline && state == status.StateDisconnected {
+			state = status.NetworkOffline
+		}
+		videoID := device.VideoID
+		if videoID == "" {
+			videoID = "-"
+		}
+		schedules := "-"
+		if len(device.Schedules) > 0 {
+			schedules = strings.Join(device.Schedules, ", ")
+		}
+
+		row := fmt.Sprintf("%s %-18s %-12s %-11s %-8s %-22s %-7s %-8s %s",
+			cursor,
+			truncate(deviceName(device), 18),
+			state,
+			videoID,
+			formatPosition(device.CurrentPosition(now)),
+			nextSegment(device.NextSegment, now),
+			ad,
+			skipping,
+			schedules,
+		)
+		s.WriteString(style.Render(truncate(row, width)))
+		if i < len(m.devices)-1 {
+			s.WriteString("\n")
+		}
+	}
+	return s.String()
+}
+
+// renderLog renders the last height log lines, or older ones when scrolled
+func (m Model) renderLog(height int) string {
+	title := "Log"
+	if m.logScroll > 0 {
+		title = fmt.Sprintf("Log (scrolled up %d lines, end: follow)", m.logScroll)
+	}
+	lines := []string{styles.Subtitle.Render(title)}
+
+	end := len(m.log) - m.logScroll
+	start := max(end-(height-1), 0)
+	width := styles.ContentWidth()
+	for _, entry := range m.log[start:end] {
+		line := entry.Time.Format("15:04:05") + " "
+		if entry.Device != "" {
+			line += "[" + entry.Device + "] "
+		}
+		line = truncate(line+entry.Message, width)
+
+		switch entry.Level {
+		case "error", "fatal", "panic":
+			lines = append(lines, styles.DiffRemoved.Render(line))
+		case "warning":
+			lines = append(lines, styles.StatusWarning.Copy().UnsetWidth().UnsetPadding().Render(line))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// deviceName returns the name of a device, or its screen ID
+func deviceName(device status.DeviceStatus) string {
+	if device.Name == "" {
+		return device.ScreenID
+	}
+	return device.Name
+}
+
+// nextSegment describes the next segment with a countdown
+func nextSegment(segment *status.SegmentStatus, now time.Time) string {
+	if segment == nil {
+		return "-"
+	}
+	category := strings.Join(segment.Categories, ",")
+	if category == "" {
+		category = "segment"
+	}
+	countdown := segment.At.Sub(now).Round(time.Second)
+	if countdown < 0 {
+		countdown = 0
+	}
+	return truncate(fmt.Sprintf("%s %s in %s", category, segment.Action, countdown), 22)
+}
+
+// formatPosition formats seconds as m:ss or h:mm:ss
+func formatPosition(seconds float64) string {
+	total := int(seconds)
+	if total < 3600 {
+		return fmt.Sprintf("%d:%02d", total/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
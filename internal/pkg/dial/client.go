@@ -3,11 +3,15 @@ package dial
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
 )
 
 const (
@@ -16,13 +20,6 @@ const (
 	searchTarget     = "urn:dial-multiscreen-org:service:dial:1"
 )
 
-// Device represents a discovered YouTube TV device
-type Device struct {
-	ScreenID string
-	Name     string
-	Offset   int
-}
-
 // Handler handles SSDP responses
 type Handler struct {
 	devices []string
@@ -47,38 +44,84 @@ func (h *Handler) HandleResponse(headers map[string]string) {
 	}
 }
 
-// getLocalIP returns the local IP address
-func getLocalIP() (string, error) {
-	conn, err := net.Dial("udp", "8.8.8.8:80")
+// searchTimeout is how long responses to an M-SEARCH are collected
+const searchTimeout = 4 * time.Second
+
+// Discover searches for YouTube TV devices on every network interface
+// selected by filter. Devices answering on more than one interface are
+// returned once.
+func Discover(ctx context.Context, client *http.Client, filter InterfaceFilter) ([]Device, error) {
+	interfaces, err := searchInterfaces(filter)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer conn.Close()
-
-	localAddr := conn.LocalAddr().(*net.UDPAddr)
-	return localAddr.IP.String(), nil
-}
-
-// Discover searches for YouTube TV devices on the network
-func Discover(ctx context.Context, client *http.Client) ([]Device, error) {
-	handler := NewHandler()
-	handler.Clear()
 
-	// Get local IP address
-	localIP, err := getLocalIP()
+	locations, err := searchLocations(ctx, interfaces)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get local IP: %w", err)
+		return nil, err
 	}
 
-	// Create UDP connection
-	addr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", localIP, 0))
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve UDP address: %w", err)
+	// Process discovered devices
+	var devices []Device
+	screenIDs := make(map[string]bool)
+	for _, location := range locations {
+		device, err := findYouTubeApp(ctx, client, location)
+		if err != nil || screenIDs[device.ScreenID] {
+			continue
+		}
+		screenIDs[device.ScreenID] = true
+		devices = append(devices, device)
 	}
 
-	conn, err := net.ListenUDP("udp4", addr)
+	return devices, nil
+}
+
+// searchLocations searches every interface at once and returns the merged
+// description locations. It fails only if the search failed on every
+// interface.
+func searchLocations(ctx context.Context, interfaces []searchInterface) ([]string, error) {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		locations []string
+		seen      = make(map[string]bool)
+		errs      []error
+	)
+	for _, iface := range interfaces {
+		wg.Add(1)
+		go func(iface searchInterface) {
+			defer wg.Done()
+			handler := NewHandler()
+			err := search(ctx, iface, handler)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+				return
+			}
+			for _, location := range handler.devices {
+				if !seen[location] {
+					seen[location] = true
+					locations = append(locations, location)
+				}
+			}
+		}(iface)
+	}
+	wg.Wait()
+	if len(errs) == len(interfaces) {
+		return nil, errors.Join(errs...)
+	}
+	return locations, nil
+}
+
+// search sends an M-SEARCH request from an interface and passes the responses
+// to handler until searchTimeout or ctx is done
+func search(ctx context.Context, iface searchInterface, handler *Handler) error {
+	// Multicast is sent out of the interface owning the bound address
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: iface.IP})
 	if err != nil {
-		return nil, fmt.Errorf("failed to listen UDP: %w", err)
+		return fmt.Errorf("failed to listen UDP: %w", err)
 	}
 	defer conn.Close()
 
@@ -93,18 +136,19 @@ func Discover(ctx context.Context, client *http.Client) ([]Device, error) {
 		multicastAddress, port, searchTarget)
 
 	// Send M-SEARCH request
-	multicastAddr, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", multicastAddress, port))
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve multicast address: %w", err)
-	}
-
-	_, err = conn.WriteToUDP([]byte(searchRequest), multicastAddr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send M-SEARCH request: %w", err)
+	multicastAddr := &net.UDPAddr{IP: net.ParseIP(multicastAddress), Port: port}
+	if _, err := conn.WriteToUDP([]byte(searchRequest), multicastAddr); err != nil {
+		return fmt.Errorf("failed to send M-SEARCH request: %w", err)
 	}
 
 	// Set read deadline
-	conn.SetReadDeadline(time.Now().Add(4 * time.Second))
+	deadline := time.Now().Add(searchTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
 
 	// Read responses
 	buffer := make([]byte, 1500)
@@ -112,94 +156,97 @@ func Discover(ctx context.Context, client *http.Client) ([]Device, error) {
 		n, _, err := conn.ReadFromUDP(buffer)
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				break
+				return nil
 			}
-			return nil, fmt.Errorf("failed to read response: %w", err)
+			return fmt.Errorf("failed to read response: %w", err)
 		}
 
 		response := string(buffer[:n])
 		headers := parseSSDPHeaders(response)
 		handler.HandleResponse(headers)
 	}
+}
 
-	// Process discovered devices
-	var devices []Device
-	for _, location := range handler.devices {
-		device, err := findYouTubeApp(ctx, client, location)
-		if err != nil {
-			continue
-		}
-		devices = append(devices, device)
-	}
+// deviceDescription is the UPnP device description served at a location
+type deviceDescription struct {
+	XMLName xml.Name `xml:"root"`
+	Device  struct {
+		FriendlyName string `xml:"friendlyName"`
+		Manufacturer string `xml:"manufacturer"`
+		ModelName    string `xml:"modelName"`
+		UDN          string `xml:"UDN"`
+	} `xml:"device"`
+}
 
-	return devices, nil
+// appDescription is the DIAL description of the YouTube app
+type appDescription struct {
+	XMLName        xml.Name `xml:"service"`
+	Name           string   `xml:"name"`
+	State          string   `xml:"state"`
+	AdditionalData struct {
+		ScreenID string `xml:"screenId"`
+	} `xml:"additionalData"`
 }
 
-// findYouTubeApp finds YouTube app information from a device location
+// findYouTubeApp reads the device description at location and the YouTube
+// app of the device
 func findYouTubeApp(ctx context.Context, client *http.Client, location string) (Device, error) {
-	// Get device description
-	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
-	if err != nil {
-		return Device{}, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := client.Do(req)
+	var desc deviceDescription
+	resp, err := getXML(ctx, client, location, &desc)
 	if err != nil {
 		return Device{}, fmt.Errorf("failed to get device description: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var deviceDesc struct {
-		Root struct {
-			Device struct {
-				FriendlyName string `xml:"friendlyName"`
-			} `xml:"device"`
-		} `xml:"root"`
+	// DIAL devices announce their REST service in the Application-URL header
+	appURL := resp.Header.Get("Application-URL")
+	if appURL == "" {
+		return Device{}, fmt.Errorf("no application URL found")
 	}
 
-	if err := xml.NewDecoder(resp.Body).Decode(&deviceDesc); err != nil {
-		return Device{}, fmt.Errorf("failed to decode device description: %w", err)
+	var app appDescription
+	if _, err := getXML(ctx, client, strings.TrimSuffix(appURL, "/")+"/YouTube", &app); err != nil {
+		return Device{}, fmt.Errorf("failed to get YouTube app info: %w", err)
+	}
+	if app.AdditionalData.ScreenID == "" {
+		return Device{}, fmt.Errorf("YouTube app has no screen ID")
 	}
 
-	// Get YouTube app URL
-	appURL := resp.Header.Get("application-url")
-	if appURL == "" {
-		return Device{}, fmt.Errorf("no application URL found")
+	name := strings.TrimSpace(desc.Device.FriendlyName)
+	if name == "" {
+		name = strings.TrimSpace(desc.Device.ModelName)
 	}
+	return Device{
+		ScreenID:       app.AdditionalData.ScreenID,
+		Name:           name,
+		Offset:         config.DefaultOffset,
+		Manufacturer:   strings.TrimSpace(desc.Device.Manufacturer),
+		ModelName:      strings.TrimSpace(desc.Device.ModelName),
+		UDN:            strings.TrimSpace(desc.Device.UDN),
+		ApplicationURL: appURL,
+		Location:       location,
+	}, nil
+}
 
-	youtubeURL := appURL + "YouTube"
-	req, err = http.NewRequestWithContext(ctx, "GET", youtubeURL, nil)
+// getXML gets url and decodes the XML body into v
+func getXML(ctx context.Context, client *http.Client, url string, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return Device{}, fmt.Errorf("failed to create YouTube request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return Device{}, fmt.Errorf("failed to get YouTube app info: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return Device{}, fmt.Errorf("YouTube app not found")
-	}
-
-	var youtubeInfo struct {
-		Service struct {
-			AdditionalData struct {
-				ScreenID string `xml:"screenId"`
-			} `xml:"additionalData"`
-		} `xml:"service"`
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
 	}
-
-	if err := xml.NewDecoder(resp.Body).Decode(&youtubeInfo); err != nil {
-		return Device{}, fmt.Errorf("failed to decode YouTube info: %w", err)
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to decode XML: %w", err)
 	}
-
-	return Device{
-		ScreenID: youtubeInfo.Service.AdditionalData.ScreenID,
-		Name:     deviceDesc.Root.Device.FriendlyName,
-		Offset:   0,
-	}, nil
+	return resp, nil
 }
 
 // parseSSDPHeaders parses SSDP response headers
@@ -0,0 +1,190 @@
+package dial
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+)
+
+// descriptionXML is a UPnP device description as served by a TV
+const descriptionXML = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:dial-multiscreen-org:device:dial:1</deviceType>
+    <friendlyName> Living Room TV </friendlyName>
+    <manufacturer>Acme</manufacturer>
+    <modelName>X100</modelName>
+    <UDN>uuid:1234</UDN>
+  </device>
+</root>`
+
+// youTubeAppXML is the DIAL description of the YouTube app
+const youTubeAppXML = `<?xml version="1.0"?>
+<service xmlns="urn:dial-multiscreen-org:schemas:dial">
+  <name>YouTube</name>
+  <state>running</state>
+  <additionalData><screenId>screen</screenId></additionalData>
+</service>`
+
+func TestDeviceDescription(t *testing.T) {
+	var desc deviceDescription
+	if err := xml.Unmarshal([]byte(descriptionXML), &desc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if desc.Device.FriendlyName != " Living Room TV " || desc.Device.Manufacturer != "Acme" ||
+		desc.Device.ModelName != "X100" || desc.Device.UDN != "uuid:1234" {
+		t.Errorf("device = %+v", desc.Device)
+	}
+}
+
+// fakeTV serves a device description and the YouTube app of a TV
+type fakeTV struct {
+	description string
+	// appURL is the Application-URL header, with {url} replaced by the
+	// server URL
+	appURL string
+	app    string
+}
+
+func (f fakeTV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/dd.xml":
+		if f.appURL != "" {
+			w.Header().Set("Application-URL", strings.ReplaceAll(f.appURL, "{url}", "http://"+r.Host))
+		}
+		w.Write([]byte(f.description))
+	case "/apps/YouTube":
+		if f.app == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(f.app))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestFindYouTubeApp(t *testing.T) {
+	tests := []struct {
+		name    string
+		tv      fakeTV
+		want    Device
+		wantErr string
+	}{
+		{
+			name: "device",
+			tv:   fakeTV{description: descriptionXML, appURL: "{url}/apps/", app: youTubeAppXML},
+			want: Device{
+				ScreenID:     "screen",
+				Name:         "Living Room TV",
+				Offset:       config.DefaultOffset,
+				Manufacturer: "Acme",
+				ModelName:    "X100",
+				UDN:          "uuid:1234",
+			},
+		},
+		{
+			name: "application URL without a trailing slash",
+			tv:   fakeTV{description: descriptionXML, appURL: "{url}/apps", app: youTubeAppXML},
+			want: Device{
+				ScreenID:     "screen",
+				Name:         "Living Room TV",
+				Offset:       config.DefaultOffset,
+				Manufacturer: "Acme",
+				ModelName:    "X100",
+				UDN:          "uuid:1234",
+			},
+		},
+		{
+			name: "model name without a friendly name",
+			tv: fakeTV{
+				description: strings.Replace(descriptionXML, " Living Room TV ", "", 1),
+				appURL:      "{url}/apps/",
+				app:         youTubeAppXML,
+			},
+			want: Device{
+				ScreenID:     "screen",
+				Name:         "X100",
+				Offset:       config.DefaultOffset,
+				Manufacturer: "Acme",
+				ModelName:    "X100",
+				UDN:          "uuid:1234",
+			},
+		},
+		{
+			name:    "no application URL",
+			tv:      fakeTV{description: descriptionXML, app: youTubeAppXML},
+			wantErr: "no application URL",
+		},
+		{
+			name:    "no YouTube app",
+			tv:      fakeTV{description: descriptionXML, appURL: "{url}/apps/"},
+			wantErr: "failed to get YouTube app info",
+		},
+		{
+			name: "no screen ID",
+			tv: fakeTV{
+				description: descriptionXML,
+				appURL:      "{url}/apps/",
+				app:         strings.Replace(youTubeAppXML, "<screenId>screen</screenId>", "", 1),
+			},
+			wantErr: "no screen ID",
+		},
+		{
+			name:    "invalid description",
+			tv:      fakeTV{description: "<root><device>", appURL: "{url}/apps/", app: youTubeAppXML},
+			wantErr: "failed to get device description",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(test.tv)
+			defer server.Close()
+
+			location := server.URL + "/dd.xml"
+			device, err := findYouTubeApp(context.Background(), server.Client(), location)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findYouTubeApp: %v", err)
+			}
+
+			test.want.Location = location
+			test.want.ApplicationURL = strings.ReplaceAll(test.tv.appURL, "{url}", server.URL)
+			if device != test.want {
+				t.Errorf("device = %+v, want %+v", device, test.want)
+			}
+		})
+	}
+}
+
+func TestManagedDevice(t *testing.T) {
+	d := Device{
+		ScreenID:     "screen",
+		Name:         "TV",
+		Manufacturer: "Acme",
+		ModelName:    "X100",
+		Location:     "http://192.168.1.20:8008/dd.xml",
+	}
+	managed := d.ManagedDevice()
+	if managed.ID != "screen" || managed.Name != "TV" || managed.Model != "Acme X100" {
+		t.Errorf("managed device = %+v", managed)
+	}
+	if managed.CustomData["address"] != "192.168.1.20" {
+		t.Errorf("address = %v, want 192.168.1.20", managed.CustomData["address"])
+	}
+	if cfg := d.DeviceConfig(); cfg.ScreenID != "screen" || cfg.Name != "TV" {
+		t.Errorf("device config = %+v", cfg)
+	}
+}
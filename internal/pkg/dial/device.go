@@ -0,0 +1,62 @@
+package dial
+
+import (
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/device"
+)
+
+// Device represents a discovered YouTube TV device
+type Device struct {
+	ScreenID string
+	Name     string
+	Offset   float64
+
+	// Manufacturer, ModelName and UDN come from the UPnP device description
+	Manufacturer string
+	ModelName    string
+	UDN          string
+	// ApplicationURL is the DIAL REST service of the device and Location the
+	// URL of its device description
+	ApplicationURL string
+	Location       string
+}
+
+// Model returns the manufacturer and model name of the device
+func (d Device) Model() string {
+	return strings.TrimSpace(d.Manufacturer + " " + d.ModelName)
+}
+
+// Address returns the host of the device description location
+func (d Device) Address() string {
+	return locationHost(d.Location)
+}
+
+// DeviceConfig returns the config entry for the device
+func (d Device) DeviceConfig() config.DeviceConfig {
+	return config.DeviceConfig{
+		Name:     d.Name,
+		Offset:   d.Offset,
+		ScreenID: d.ScreenID,
+	}
+}
+
+// ManagedDevice returns the device as registered with a device.DeviceManager.
+// The custom data holds the UPnP details and the address of the device.
+func (d Device) ManagedDevice() *device.Device {
+	return &device.Device{
+		ID:           d.ScreenID,
+		Name:         d.Name,
+		Model:        d.Model(),
+		Capabilities: []string{"dial", "youtube"},
+		CustomData: map[string]interface{}{
+			"manufacturer":    d.Manufacturer,
+			"model_name":      d.ModelName,
+			"udn":             d.UDN,
+			"application_url": d.ApplicationURL,
+			"location":        d.Location,
+			"address":         d.Address(),
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package dial
+
+import (
+	"fmt"
+	"net"
+	"path"
+)
+
+// InterfaceFilter selects the network interfaces searched for devices by
+// name. Names may be glob patterns such as "docker*".
+type InterfaceFilter struct {
+	// Allow lists the interfaces to search; empty allows every interface
+	Allow []string
+	// Deny lists interfaces never searched, even if allowed
+	Deny []string
+}
+
+// Match reports whether the filter selects the interface with the given name
+func (f InterfaceFilter) Match(name string) bool {
+	if matchAny(f.Deny, name) {
+		return false
+	}
+	return len(f.Allow) == 0 || matchAny(f.Allow, name)
+}
+
+// matchAny reports whether name matches any of the patterns. Invalid
+// patterns only match the identical name.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); ok || (err != nil && pattern == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchInterface is an interface devices are searched on, with the IPv4
+// address the search is sent from
+type searchInterface struct {
+	net.Interface
+	IP net.IP
+}
+
+// searchInterfaces returns the interfaces selected by filter with their first
+// IPv4 address
+func searchInterfaces(filter InterfaceFilter) ([]searchInterface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var selected []searchInterface
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if !filter.Match(iface.Name) {
+			continue
+		}
+		ip := interfaceIPv4(iface)
+		if ip == nil {
+			continue
+		}
+		selected = append(selected, searchInterface{Interface: iface, IP: ip})
+	}
+	if len(selected) == 0 {
+		if len(filter.Allow) > 0 || len(filter.Deny) > 0 {
+			return nil, fmt.Errorf("no multicast network interface with an IPv4 address matches the interface filter")
+		}
+		return nil, fmt.Errorf("no multicast network interface with an IPv4 address found")
+	}
+	return selected, nil
+}
+
+// interfaceIPv4 returns the first IPv4 address of an interface, or nil
+func interfaceIPv4(iface net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			if ip := ipNet.IP.To4(); ip != nil {
+				return ip
+			}
+		}
+	}
+	return nil
+}
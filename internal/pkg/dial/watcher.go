@@ -0,0 +1,352 @@
+package dial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of change reported by a Watcher
+type EventType int
+
+const (
+	// EventAdded is reported for a device seen for the first time
+	EventAdded EventType = iota
+	// EventRemoved is reported for a device that left the network
+	EventRemoved
+	// EventChanged is reported for a known device at a new location, e.g.
+	// after its IP address changed
+	EventChanged
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventRemoved:
+		return "removed"
+	case EventChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event reports a change of a device on the network
+type Event struct {
+	Type   EventType
+	Device Device
+	// PreviousLocation is the location of the device before EventChanged
+	PreviousLocation string
+}
+
+// Address returns the address of the device
+func (e Event) Address() string {
+	return e.Device.Address()
+}
+
+// PreviousAddress returns the host of the previous location of a changed
+// device
+func (e Event) PreviousAddress() string {
+	return locationHost(e.PreviousLocation)
+}
+
+// notifyBuffer is the number of NOTIFY messages queued while a probe runs
+const notifyBuffer = 64
+
+// lookupTimeout bounds fetching the description of a single device, so an
+// unresponsive TV does not hold up the others
+const lookupTimeout = 5 * time.Second
+
+// Watcher keeps track of the YouTube TV devices on the network. It listens
+// for SSDP NOTIFY messages and searches the network periodically.
+type Watcher struct {
+	client *http.Client
+	filter InterfaceFilter
+
+	// known holds the devices on the network by screen ID
+	known map[string]Device
+	// ignored holds the locations that are not YouTube devices, until the
+	// next probe
+	ignored map[string]bool
+	// pending holds the announced locations being looked up
+	pending map[string]bool
+}
+
+// lookupResult is the outcome of looking up the device at a location
+type lookupResult struct {
+	location string
+	device   Device
+	err      error
+}
+
+// NewWatcher creates a watcher searching the interfaces selected by filter
+func NewWatcher(client *http.Client, filter InterfaceFilter) *Watcher {
+	return &Watcher{
+		client:  client,
+		filter:  filter,
+		known:   make(map[string]Device),
+		ignored: make(map[string]bool),
+		pending: make(map[string]bool),
+	}
+}
+
+// Run listens for SSDP NOTIFY messages and searches the network every
+// interval until ctx is done, calling onEvent for every change. The first
+// search reports every device found as added. Run fails if the SSDP
+// multicast group cannot be joined on any interface.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, onEvent func(Event)) error {
+	interfaces, err := searchInterfaces(w.filter)
+	if err != nil {
+		return err
+	}
+
+	// Stop the listeners before waiting for them
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	notifies := make(chan map[string]string, notifyBuffer)
+	lookups := make(chan lookupResult)
+
+	var errs []error
+	for _, iface := range interfaces {
+		conn, err := net.ListenMulticastUDP("udp4", &iface.Interface, &net.UDPAddr{IP: net.ParseIP(multicastAddress), Port: port})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to join multicast group: %w", iface.Name, err))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			listen(ctx, conn, notifies)
+		}()
+	}
+	if len(errs) == len(interfaces) {
+		return errors.Join(errs...)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.probe(ctx, interfaces, onEvent)
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case headers := <-notifies:
+				location := w.handleNotify(headers, onEvent)
+				if location == "" {
+					continue
+				}
+				// Look announced devices up aside, so a slow TV does not
+				// hold up the messages of the others
+				w.pending[location] = true
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result := w.lookup(ctx, location)
+					select {
+					case lookups <- result:
+					case <-ctx.Done():
+					}
+				}()
+			case result := <-lookups:
+				delete(w.pending, result.location)
+				if result.err != nil {
+					w.ignored[result.location] = true
+					continue
+				}
+				w.update(result.device, onEvent)
+			case <-ticker.C:
+				break wait
+			}
+		}
+	}
+}
+
+// listen passes the NOTIFY messages received on conn to notifies until ctx
+// is done
+func listen(ctx context.Context, conn *net.UDPConn, notifies chan<- map[string]string) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	buffer := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			return
+		}
+
+		message := string(buffer[:n])
+		if !strings.HasPrefix(message, "NOTIFY ") {
+			continue
+		}
+		select {
+		case notifies <- parseSSDPHeaders(message):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probe searches the network and checks the known devices are still
+// reachable, reporting every device that appeared, moved or left
+func (w *Watcher) probe(ctx context.Context, interfaces []searchInterface, onEvent func(Event)) {
+	// A failed search still leaves the known devices to check
+	locations, _ := searchLocations(ctx, interfaces)
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Devices that did not answer the search are looked up at their last
+	// location, so a lost response does not remove them
+	for _, known := range w.known {
+		locations = append(locations, known.Location)
+	}
+
+	var unique []string
+	probed := make(map[string]bool)
+	for _, location := range locations {
+		if !probed[location] {
+			probed[location] = true
+			unique = append(unique, location)
+		}
+	}
+
+	results := make([]lookupResult, len(unique))
+	var wg sync.WaitGroup
+	for i, location := range unique {
+		wg.Add(1)
+		go func(i int, location string) {
+			defer wg.Done()
+			results[i] = w.lookup(ctx, location)
+		}(i, location)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	found := make(map[string]Device)
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		device := result.device
+		// Prefer the known location of devices answering at several
+		if previous, ok := found[device.ScreenID]; ok && w.isKnownAt(previous) {
+			continue
+		}
+		found[device.ScreenID] = device
+	}
+
+	for _, device := range found {
+		w.update(device, onEvent)
+	}
+	// Give every other location another chance
+	w.ignored = make(map[string]bool)
+
+	for screenID, known := range w.known {
+		if _, ok := found[screenID]; !ok {
+			w.remove(known, onEvent)
+		}
+	}
+}
+
+// handleNotify handles an ssdp:alive or ssdp:byebye message. It returns the
+// location of an announced device to look up, if any.
+func (w *Watcher) handleNotify(headers map[string]string, onEvent func(Event)) string {
+	switch headers["nts"] {
+	case "ssdp:alive":
+		location := headers["location"]
+		if location == "" || w.ignored[location] || w.pending[location] || w.knownAt(location) {
+			return ""
+		}
+		return location
+
+	case "ssdp:byebye":
+		// The USN starts with the UDN of the device description
+		udn := usnUUID(headers["usn"])
+		if udn == "" {
+			return ""
+		}
+		for _, known := range w.known {
+			if known.UDN == udn {
+				w.remove(known, onEvent)
+			}
+		}
+	}
+	return ""
+}
+
+// lookup fetches the YouTube app of the device at location, giving up after
+// lookupTimeout
+func (w *Watcher) lookup(ctx context.Context, location string) lookupResult {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	device, err := findYouTubeApp(ctx, w.client, location)
+	return lookupResult{location: location, device: device, err: err}
+}
+
+// update records a found device and reports it if it is new or moved
+func (w *Watcher) update(device Device, onEvent func(Event)) {
+	previous, ok := w.known[device.ScreenID]
+	w.known[device.ScreenID] = device
+	switch {
+	case !ok:
+		onEvent(Event{Type: EventAdded, Device: device})
+	case previous.Location != device.Location:
+		onEvent(Event{Type: EventChanged, Device: device, PreviousLocation: previous.Location})
+	}
+}
+
+// remove forgets a device and reports it as removed
+func (w *Watcher) remove(device Device, onEvent func(Event)) {
+	delete(w.known, device.ScreenID)
+	onEvent(Event{Type: EventRemoved, Device: device})
+}
+
+// knownAt reports whether a device is known at a location
+func (w *Watcher) knownAt(location string) bool {
+	for _, known := range w.known {
+		if known.Location == location {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownAt reports whether a device is known at its location
+func (w *Watcher) isKnownAt(device Device) bool {
+	known, ok := w.known[device.ScreenID]
+	return ok && known.Location == device.Location
+}
+
+// usnUUID returns the device UUID of a USN header such as
+// "uuid:1234::urn:dial-multiscreen-org:service:dial:1"
+func usnUUID(usn string) string {
+	uuid, _, _ := strings.Cut(usn, "::")
+	return uuid
+}
+
+// locationHost returns the host of a location URL without the port
+func locationHost(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
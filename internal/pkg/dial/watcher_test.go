@@ -0,0 +1,59 @@
+package dial
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHandleNotify(t *testing.T) {
+	known := Device{ScreenID: "screen", UDN: "uuid:1234", Location: "http://192.168.1.20:8008/dd.xml"}
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		wantLocation string
+		wantRemoved  bool
+	}{
+		{
+			name:         "new location",
+			headers:      map[string]string{"nts": "ssdp:alive", "location": "http://192.168.1.30:8008/dd.xml"},
+			wantLocation: "http://192.168.1.30:8008/dd.xml",
+		},
+		{
+			name:    "location being looked up",
+			headers: map[string]string{"nts": "ssdp:alive", "location": "http://192.168.1.40:8008/dd.xml"},
+		},
+		{
+			name:    "ignored location",
+			headers: map[string]string{"nts": "ssdp:alive", "location": "http://192.168.1.50:8008/dd.xml"},
+		},
+		{
+			name:    "known location",
+			headers: map[string]string{"nts": "ssdp:alive", "location": known.Location},
+		},
+		{
+			name:        "byebye",
+			headers:     map[string]string{"nts": "ssdp:byebye", "usn": "uuid:1234::urn:dial-multiscreen-org:service:dial:1"},
+			wantRemoved: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := NewWatcher(http.DefaultClient, InterfaceFilter{})
+			w.known[known.ScreenID] = known
+			w.pending["http://192.168.1.40:8008/dd.xml"] = true
+			w.ignored["http://192.168.1.50:8008/dd.xml"] = true
+
+			var events []Event
+			location := w.handleNotify(test.headers, func(e Event) { events = append(events, e) })
+			if location != test.wantLocation {
+				t.Errorf("location = %q, want %q", location, test.wantLocation)
+			}
+			removed := len(events) == 1 && events[0].Type == EventRemoved
+			if removed != test.wantRemoved || (!test.wantRemoved && len(events) > 0) {
+				t.Errorf("events = %+v", events)
+			}
+		})
+	}
+}
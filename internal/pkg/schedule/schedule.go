@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+)
+
+// State is the effective configuration of a device at a point in time
+type State struct {
+	Config *config.Config
+	// Active lists the names of the schedules in effect, in the order applied
+	Active []string
+}
+
+// String returns a human readable description of the active schedules
+func (s State) String() string {
+	if len(s.Active) == 0 {
+		return "no schedule active"
+	}
+	return "active schedules: " + strings.Join(s.Active, ", ")
+}
+
+// Engine switches the effective settings of devices according to the
+// configured schedules
+type Engine struct {
+	cfg    *config.Config
+	loc    *time.Location
+	now    func() time.Time
+	mu     sync.Mutex
+	active map[string]string
+}
+
+// NewEngine creates a new schedule engine evaluating schedules in the given
+// location, the local timezone if nil
+func NewEngine(cfg *config.Config, loc *time.Location) (*Engine, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	for _, s := range cfg.Schedules {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if s.Profile != "" {
+			if _, ok := cfg.Profiles[s.Profile]; !ok {
+				return nil, fmt.Errorf("schedule %q: unknown profile %q", s.Name, s.Profile)
+			}
+		}
+	}
+
+	return &Engine{
+		cfg:    cfg,
+		loc:    loc,
+		now:    time.Now,
+		active: make(map[string]string),
+	}, nil
+}
+
+// Resolve returns the effective state of a device at time t. Overrides are
+// applied in order: global options, the device's own overrides, then each
+// active schedule in config order, so later schedules win.
+func (e *Engine) Resolve(device config.DeviceConfig, t time.Time) State {
+	effective := e.cfg.ForDevice(device)
+	active := make([]string, 0)
+
+	local := t.In(e.loc)
+	for _, s := range e.cfg.Schedules {
+		if !s.AppliesTo(device) || !s.ActiveAt(local) {
+			continue
+		}
+		if s.Profile != "" {
+			effective = effective.WithOverrides(e.cfg.Profiles[s.Profile])
+		}
+		effective = effective.WithOverrides(s.Overrides)
+		active = append(active, s.Name)
+	}
+
+	return State{Config: effective, Active: active}
+}
+
+// Current returns the effective state of a device now
+func (e *Engine) Current(device config.DeviceConfig) State {
+	return e.Resolve(device, e.now())
+}
+
+// Seed records state as already reported for a device, so Run only reports
+// the device once its active schedules change from state
+func (e *Engine) Seed(device config.DeviceConfig, state State) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.active[device.ScreenID] = strings.Join(state.Active, "\x00")
+}
+
+// Run re-evaluates the schedules every interval until ctx is done, calling
+// onChange for every device whose active schedules changed since the last
+// evaluation. The first evaluation reports every device.
+func (e *Engine) Run(ctx context.Context, interval time.Duration, onChange func(device config.DeviceConfig, state State)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluate(onChange)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate resolves every device and reports those whose schedules changed
+func (e *Engine) evaluate(onChange func(device config.DeviceConfig, state State)) {
+	now := e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, device := range e.cfg.Devices {
+		state := e.Resolve(device, now)
+		key := strings.Join(state.Active, "\x00")
+		if previous, ok := e.active[device.ScreenID]; ok && previous == key {
+			continue
+		}
+		e.active[device.ScreenID] = key
+		onChange(device, state)
+	}
+}
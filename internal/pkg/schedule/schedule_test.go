@@ -0,0 +1,24 @@
+package schedule
+
+import (
+	"testing"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+)
+
+func TestSeedSkipsTheFirstReport(t *testing.T) {
+	cfg := &config.Config{Devices: []config.DeviceConfig{{ScreenID: "a"}, {ScreenID: "b"}}}
+	engine, err := NewEngine(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine.Seed(cfg.Devices[0], engine.Current(cfg.Devices[0]))
+
+	var reported []string
+	engine.evaluate(func(device config.DeviceConfig, state State) {
+		reported = append(reported, device.ScreenID)
+	})
+	if len(reported) != 1 || reported[0] != "b" {
+		t.Errorf("reported %q, want only the device that was not seeded", reported)
+	}
+}
@@ -0,0 +1,213 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/dial"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+)
+
+// The functions in this file change the config the same way for the setup
+// wizard and the setup subcommands.
+
+// ErrChannelNotFound is returned when a channel search has no results
+var ErrChannelNotFound = errors.New("no channel found")
+
+// channelIDPattern matches YouTube channel IDs
+var channelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+
+// NewDevice returns the config of a newly added device
+func NewDevice(name, screenID string) config.DeviceConfig {
+	return config.DeviceConfig{
+		Name:     name,
+		Offset:   config.DefaultOffset,
+		ScreenID: screenID,
+	}
+}
+
+// DiscoverDevices finds the TVs on the local network over DIAL. TVs found
+// more than once are listed once.
+func DiscoverDevices(ctx context.Context, helper *api.APIHelper) ([]dial.Device, error) {
+	devices, err := helper.DiscoverYouTubeDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make([]dial.Device, 0, len(devices))
+	seen := make(map[string]bool)
+	for _, device := range devices {
+		if device.ScreenID == "" || seen[device.ScreenID] {
+			continue
+		}
+		seen[device.ScreenID] = true
+		unique = append(unique, device)
+	}
+	return unique, nil
+}
+
+// FindDevice returns the configured device with the given screen ID, or
+// else the one with the given name
+func FindDevice(cfg *config.Config, ref string) (config.DeviceConfig, bool) {
+	for _, device := range cfg.Devices {
+		if device.ScreenID == ref {
+			return device, true
+		}
+	}
+	for _, device := range cfg.Devices {
+		if strings.EqualFold(device.Name, ref) {
+			return device, true
+		}
+	}
+	return config.DeviceConfig{}, false
+}
+
+// AddScreen adds a TV resolved from a TV code to the config and reports
+// whether it was added. Its lounge token is left to the caller, to store
+// with StoreLoungeToken once the config is saved.
+func AddScreen(cfg *config.Config, screen *ytlounge.Screen) bool {
+	return cfg.AddDevice(NewDevice(screen.Name, screen.ScreenID))
+}
+
+// StoreLoungeToken stores the lounge token of a device in the data directory
+func StoreLoungeToken(cfg *config.Config, screenID, token string) error {
+	tokens := ytlounge.NewTokenStore(cfg.DataPath(ytlounge.TokensFileName))
+	if err := tokens.Set(screenID, token); err != nil {
+		return fmt.Errorf("failed to store lounge token: %w", err)
+	}
+	return nil
+}
+
+// DeleteLoungeToken deletes the lounge token of a device from the data
+// directory
+func DeleteLoungeToken(cfg *config.Config, screenID string) error {
+	tokens := ytlounge.NewTokenStore(cfg.DataPath(ytlounge.TokensFileName))
+	if err := tokens.Delete(screenID); err != nil {
+		return fmt.Errorf("failed to delete lounge token: %w", err)
+	}
+	return nil
+}
+
+// CategoryActions returns the action of every category with one, combining
+// the skipped categories with the category actions. Ignored categories are
+// left out.
+func CategoryActions(cfg *config.Config) map[string]string {
+	actions := make(map[string]string)
+	for _, category := range cfg.SkipCategories {
+		actions[category] = constants.ActionSkip
+	}
+	for category, action := range cfg.CategoryActions {
+		actions[category] = action
+	}
+	for category, action := range actions {
+		if action == constants.ActionIgnore {
+			delete(actions, category)
+		}
+	}
+	return actions
+}
+
+// SetCategoryActions writes category actions to the config: skipped
+// categories go to SkipCategories in the order of constants.SkipCategories,
+// other actions to CategoryActions. Categories without an action are ignored.
+// Unknown categories already in the config are kept.
+func SetCategoryActions(cfg *config.Config, actions map[string]string) {
+	skip := make([]string, 0, len(actions))
+	other := make(map[string]string)
+	for _, category := range constants.SkipCategories {
+		switch action := actions[category.ID]; action {
+		case constants.ActionSkip:
+			skip = append(skip, category.ID)
+		case "", constants.ActionIgnore:
+		default:
+			other[category.ID] = action
+		}
+	}
+	for _, id := range cfg.SkipCategories {
+		if _, known := constants.GetSkipCategoryByID(id); !known {
+			skip = append(skip, id)
+		}
+	}
+	for id, action := range cfg.CategoryActions {
+		if _, known := constants.GetSkipCategoryByID(id); !known {
+			other[id] = action
+		}
+	}
+
+	cfg.SkipCategories = skip
+	cfg.CategoryActions = nil
+	if len(other) > 0 {
+		cfg.CategoryActions = other
+	}
+}
+
+// ParseCategories parses a comma separated list of category IDs or names
+// into IDs
+func ParseCategories(list string) ([]string, error) {
+	ids := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		category, ok := constants.GetSkipCategoryByID(item)
+		if !ok {
+			for _, c := range constants.SkipCategories {
+				if strings.EqualFold(c.Name, item) || strings.EqualFold(c.ID, item) {
+					category, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown category %q, valid categories are %s",
+				item, strings.Join(constants.GetSkipCategoryIDs(), ", "))
+		}
+		ids = append(ids, category.ID)
+	}
+	return ids, nil
+}
+
+// ResolveChannel returns the channel for a channel ID, @handle or search
+// query. Channel IDs are taken as they are and @handles must match a
+// channel exactly; anything else is searched for and the best match is
+// returned. Handles and searches need an API key.
+func ResolveChannel(ctx context.Context, cfg *config.Config, query string) (types.ChannelInfo, error) {
+	query = strings.TrimSpace(query)
+	if channelIDPattern.MatchString(query) {
+		return types.ChannelInfo{ID: query}, nil
+	}
+	if cfg.APIKey == "" {
+		return types.ChannelInfo{}, fmt.Errorf("searching channels needs an API key")
+	}
+
+	helper := api.NewAPIHelper(cfg, &http.Client{Timeout: 10 * time.Second})
+	if strings.HasPrefix(query, "@") {
+		channel, ok, err := helper.ChannelByHandle(ctx, query)
+		if err != nil {
+			return types.ChannelInfo{}, err
+		}
+		if !ok {
+			return types.ChannelInfo{}, fmt.Errorf("%w with handle %s", ErrChannelNotFound, query)
+		}
+		return types.ChannelInfo{ID: channel.ID, Name: channel.Title}, nil
+	}
+
+	results, err := helper.SearchChannels(ctx, query)
+	if err != nil {
+		return types.ChannelInfo{}, err
+	}
+	if len(results) == 0 {
+		return types.ChannelInfo{}, fmt.Errorf("%w for %q", ErrChannelNotFound, query)
+	}
+	return types.ChannelInfo{ID: results[0].ID, Name: results[0].Title}, nil
+}
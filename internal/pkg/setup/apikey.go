@@ -0,0 +1,187 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// APIKeyChecker tests a YouTube Data API key. The wizard uses
+// api.CheckAPIKey against the YouTube Data API unless replaced with
+// WithAPIKeyChecker.
+type APIKeyChecker func(ctx context.Context, key string) (api.APIKeyStatus, string, error)
+
+// apiKeyTab holds the state of the YouTube API Key tab
+type apiKeyTab struct {
+	// editing is set while the key input has focus
+	editing bool
+	input   textinput.Model
+	busy    bool
+	spinner spinner.Model
+
+	// result of the last check
+	checked bool
+	status  api.APIKeyStatus
+	message string
+	err     error
+}
+
+// apiKeyCheckMsg carries the result of an API key check
+type apiKeyCheckMsg struct {
+	status  api.APIKeyStatus
+	message string
+	err     error
+}
+
+func newAPIKeyTab() apiKeyTab {
+	input := textinput.New()
+	input.Placeholder = "Enter your API key"
+	input.EchoMode = textinput.EchoPassword
+	input.EchoCharacter = '*'
+	input.Width = 45
+
+	return apiKeyTab{
+		input:   input,
+		spinner: newSpinner(),
+	}
+}
+
+// WithAPIKeyChecker returns the model with the API key check replaced, e.g.
+// by a local stand-in for the YouTube Data API
+func (m Model) WithAPIKeyChecker(check APIKeyChecker) Model {
+	m.checkAPIKey = check
+	return m
+}
+
+// runAPIKeyCheck tests an API key in the background
+func (m Model) runAPIKeyCheck(key string) tea.Cmd {
+	check := m.checkAPIKey
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+
+		status, message, err := check(ctx, key)
+		return apiKeyCheckMsg{status: status, message: message, err: err}
+	}
+}
+
+// handleAPIKeyCheck records the result of an API key check
+func (m Model) handleAPIKeyCheck(msg apiKeyCheckMsg) Model {
+	k := &m.apiKey
+	k.busy = false
+	k.checked = true
+	k.status = msg.status
+	k.message = msg.message
+	k.err = msg.err
+	return m
+}
+
+// updateAPIKeyTab handles the keys of the YouTube API Key tab
+func (m Model) updateAPIKeyTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	k := &m.apiKey
+	if k.editing {
+		switch msg.String() {
+		case "esc":
+			k.editing = false
+			k.input.Blur()
+			return m, nil
+		case "enter":
+			k.editing = false
+			k.input.Blur()
+			m.config.APIKey = strings.TrimSpace(k.input.Value())
+			k.checked = false
+			if m.config.APIKey == "" {
+				m.setStatus("API key removed", false)
+				return m, nil
+			}
+			k.busy = true
+			return m, tea.Batch(k.spinner.Tick, m.runAPIKeyCheck(m.config.APIKey))
+		}
+		var cmd tea.Cmd
+		k.input, cmd = k.input.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "enter", "e":
+		k.editing = true
+		k.input.SetValue(m.config.APIKey)
+		k.input.CursorEnd()
+		return m, k.input.Focus()
+	case "t":
+		if m.config.APIKey == "" || k.busy {
+			return m, nil
+		}
+		k.busy = true
+		return m, tea.Batch(k.spinner.Tick, m.runAPIKeyCheck(m.config.APIKey))
+	}
+	return m, nil
+}
+
+func (m Model) renderAPIKeyTab() string {
+	var s strings.Builder
+	s.WriteString(styles.Title.Render("YouTube API Key") + "\n")
+	s.WriteString(styles.Subtitle.Render(
+		"You can get a YouTube Data API v3 Key from the Google Cloud Console",
+	) + "\n\n")
+
+	k := m.apiKey
+	if k.editing {
+		s.WriteString(styles.Input.Render(k.input.View()) + "\n")
+	} else {
+		key := strings.Repeat("*", len(m.config.APIKey))
+		if key == "" {
+			key = "No API key set"
+		}
+		s.WriteString(styles.Input.Render(key) + "\n")
+	}
+
+	switch {
+	case k.busy:
+		s.WriteString(styles.SelectionItem.Render(k.spinner.View() + " Checking the key..."))
+	case !k.checked:
+	case k.err != nil:
+		s.WriteString(styles.StatusError.Render("Could not check the key: " + k.err.Error()))
+	case k.status == api.APIKeyValid:
+		s.WriteString(styles.StatusSuccess.Render("The key is valid"))
+	default:
+		s.WriteString(styles.StatusError.Render(APIKeyProblem(k.status, k.message)))
+	}
+	return s.String()
+}
+
+// APIKeyProblem explains a key that is not valid
+func APIKeyProblem(status api.APIKeyStatus, message string) string {
+	var problem string
+	switch status {
+	case api.APIKeyInvalid:
+		problem = "The key is invalid. Copy it again from the Google Cloud Console"
+	case api.APIKeyQuotaExceeded:
+		problem = "The key is valid, but its daily quota is used up. It will work again after the quota resets"
+	case api.APIKeyRestricted:
+		problem = "The key may not use the YouTube Data API. Enable the API for its project and check the key's restrictions"
+	default:
+		problem = fmt.Sprintf("The key is %s", status)
+	}
+	if message != "" {
+		problem += " (" + message + ")"
+	}
+	return problem
+}
+
+// apiKeyHelp returns the key help of the YouTube API Key tab
+func (m Model) apiKeyHelp() string {
+	if m.apiKey.editing {
+		return "enter: Save and check  esc: Cancel"
+	}
+	if m.config.APIKey == "" {
+		return "enter: Edit"
+	}
+	return "enter: Edit  t: Check key"
+}
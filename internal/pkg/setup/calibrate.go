@@ -0,0 +1,169 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sirupsen/logrus"
+)
+
+// calibrationSeeks is the number of seeks of a calibration run
+const calibrationSeeks = 5
+
+// calibration holds the state of an offset calibration run
+type calibration struct {
+	active   bool
+	screenID string
+	name     string
+	cancel   context.CancelFunc
+	events   chan tea.Msg
+
+	step   ytlounge.CalibrationStep
+	done   bool
+	offset float64
+	err    error
+}
+
+// calibrationStepMsg reports the progress of a calibration run
+type calibrationStepMsg struct {
+	events chan tea.Msg
+	step   ytlounge.CalibrationStep
+}
+
+// calibrationDoneMsg carries the result of a calibration run
+type calibrationDoneMsg struct {
+	events chan tea.Msg
+	offset float64
+	err    error
+}
+
+// startCalibration starts calibrating the offset of a device in the
+// background. Progress is delivered through the events channel, which is
+// read one message at a time by waitForCalibration.
+func (m Model) startCalibration(device config.DeviceConfig) (Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan tea.Msg, calibrationSeeks+1)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	lounge := ytlounge.NewYtLoungeApi(ytlounge.NewScreenClient(m.config, device.ScreenID, ytlounge.NewTokenStore(m.config.DataPath(ytlounge.TokensFileName))), m.api, logger)
+	videoID := m.config.CalibrationVideo
+	go func() {
+		offset, err := lounge.Calibrate(ctx, videoID, calibrationSeeks, func(step ytlounge.CalibrationStep) {
+			events <- calibrationStepMsg{events: events, step: step}
+		})
+		events <- calibrationDoneMsg{events: events, offset: offset, err: err}
+	}()
+
+	name := device.Name
+	if name == "" {
+		name = device.ScreenID
+	}
+	m.devices.calibration = calibration{
+		active:   true,
+		screenID: device.ScreenID,
+		name:     name,
+		cancel:   cancel,
+		events:   events,
+	}
+	m.setStatus("", false)
+	return m, tea.Batch(m.devices.spinner.Tick, waitForCalibration(events))
+}
+
+// waitForCalibration waits for the next message of a calibration run.
+// Messages of cancelled runs are recognised by their events channel.
+func waitForCalibration(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// handleCalibrationStep records the progress of a calibration run
+func (m Model) handleCalibrationStep(msg calibrationStepMsg) (Model, tea.Cmd) {
+	c := &m.devices.calibration
+	if !c.active || msg.events != c.events {
+		return m, nil
+	}
+	c.step = msg.step
+	return m, waitForCalibration(c.events)
+}
+
+// handleCalibrationDone records the suggested offset of a calibration run
+func (m Model) handleCalibrationDone(msg calibrationDoneMsg) Model {
+	c := &m.devices.calibration
+	if !c.active || msg.events != c.events {
+		return m
+	}
+	c.cancel()
+	c.done = true
+	c.err = msg.err
+	// Round to what a user would type
+	c.offset = math.Round(msg.offset*100) / 100
+	return m
+}
+
+// updateCalibration handles the keys of a calibration run
+func (m Model) updateCalibration(msg tea.KeyMsg) (Model, tea.Cmd) {
+	c := &m.devices.calibration
+	switch msg.String() {
+	case "esc":
+		c.cancel()
+		*c = calibration{}
+	case "enter":
+		if !c.done || c.err != nil {
+			return m, nil
+		}
+		for i := range m.config.Devices {
+			if m.config.Devices[i].ScreenID == c.screenID {
+				m.config.Devices[i].Offset = c.offset
+			}
+		}
+		m.setStatus(fmt.Sprintf("Offset of %s set to %.2f s", c.name, c.offset), false)
+		*c = calibration{}
+	}
+	return m, nil
+}
+
+// renderCalibration renders the progress or result of a calibration run
+func (m Model) renderCalibration() string {
+	c := m.devices.calibration
+	var s strings.Builder
+	s.WriteString(styles.Subtitle.Render("Calibrating "+c.name) + "\n")
+
+	switch {
+	case !c.done:
+		progress := "Playing the reference video..."
+		if c.step.Seeks > 0 {
+			progress = fmt.Sprintf("Seek %d of %d, last lag %.2f s", c.step.Seek, c.step.Seeks, c.step.Lag)
+		}
+		s.WriteString(styles.SelectionItem.Render(m.devices.spinner.View() + " " + progress))
+	case c.err != nil:
+		s.WriteString(styles.StatusError.Render("Calibration failed: " + c.err.Error()))
+	default:
+		current := 0.0
+		for _, device := range m.config.Devices {
+			if device.ScreenID == c.screenID {
+				current = device.Offset
+			}
+		}
+		s.WriteString(styles.StatusSuccess.Render(fmt.Sprintf(
+			"Suggested offset: %.2f s (currently %.2f s)", c.offset, current)))
+	}
+	return s.String()
+}
+
+// calibrationHelp returns the key help of a calibration run
+func (m Model) calibrationHelp() string {
+	c := m.devices.calibration
+	if c.done && c.err == nil {
+		return "enter: Use suggested offset  esc: Discard"
+	}
+	return "esc: Cancel"
+}
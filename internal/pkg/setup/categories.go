@@ -0,0 +1,153 @@
+package setup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// categoriesTab holds the state of the Skip Categories tab
+type categoriesTab struct {
+	// actions maps category IDs to their action; categories without an
+	// entry are ignored
+	actions map[string]string
+	cursor  int
+	// picking is set while the action picker of the category under the
+	// cursor is open
+	picking    bool
+	pickCursor int
+}
+
+func newCategoriesTab(cfg *config.Config) categoriesTab {
+	return categoriesTab{actions: CategoryActions(cfg)}
+}
+
+// action returns the action of a category
+func (c categoriesTab) action(id string) string {
+	if action, ok := c.actions[id]; ok {
+		return action
+	}
+	return constants.ActionIgnore
+}
+
+// setAction sets the action of a category
+func (c *categoriesTab) setAction(id, action string) {
+	if action == constants.ActionIgnore {
+		delete(c.actions, id)
+		return
+	}
+	c.actions[id] = action
+}
+
+// apply writes the category actions to the config
+func (c categoriesTab) apply(cfg *config.Config) {
+	SetCategoryActions(cfg, c.actions)
+}
+
+// updateCategoriesTab handles the keys of the Skip Categories tab
+func (m Model) updateCategoriesTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	c := &m.categories
+	if c.picking {
+		switch msg.String() {
+		case "up", "k":
+			if c.pickCursor > 0 {
+				c.pickCursor--
+			}
+		case "down", "j":
+			if c.pickCursor < len(constants.ActionsFor(constants.SkipCategories[c.cursor].ID))-1 {
+				c.pickCursor++
+			}
+		case "enter", " ":
+			id := constants.SkipCategories[c.cursor].ID
+			c.setAction(id, constants.ActionsFor(id)[c.pickCursor])
+			c.apply(m.config)
+			c.picking = false
+		case "esc":
+			c.picking = false
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+	case "down", "j":
+		if c.cursor < len(constants.SkipCategories)-1 {
+			c.cursor++
+		}
+	case " ":
+		id := constants.SkipCategories[c.cursor].ID
+		if !constants.IsSkippable(id) {
+			m.setStatus(fmt.Sprintf("%s segments cannot be skipped or muted", constants.SkipCategories[c.cursor].Name), true)
+			return m, nil
+		}
+		if c.action(id) == constants.ActionIgnore {
+			c.setAction(id, constants.ActionSkip)
+		} else {
+			c.setAction(id, constants.ActionIgnore)
+		}
+		c.apply(m.config)
+	case "enter":
+		c.picking = true
+		c.pickCursor = 0
+		id := constants.SkipCategories[c.cursor].ID
+		current := c.action(id)
+		for i, action := range constants.ActionsFor(id) {
+			if action == current {
+				c.pickCursor = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// renderSkipCategoriesTab renders the Skip Categories tab and returns the
+// line of the category or action under the cursor
+func (m Model) renderSkipCategoriesTab() (string, int) {
+	var s strings.Builder
+	focus := 0
+	s.WriteString(styles.Title.Render("Skip Categories") + "\n")
+	s.WriteString(styles.Subtitle.Render("Select the categories you want to skip or mute") + "\n\n")
+
+	c := m.categories
+	for i, category := range constants.SkipCategories {
+		action := c.action(category.ID)
+		checked := " "
+		if action != constants.ActionIgnore {
+			checked = "x"
+		}
+		line := fmt.Sprintf("[%s] %-18s %s", checked, category.Name, action)
+		if i == c.cursor {
+			focus = strings.Count(s.String(), "\n")
+			s.WriteString(styles.SelectionItemActive.Render("> "+line) + "\n")
+		} else {
+			s.WriteString(styles.SelectionItem.Render("  "+line) + "\n")
+		}
+
+		if i == c.cursor && c.picking {
+			for j, option := range constants.ActionsFor(category.ID) {
+				if j == c.pickCursor {
+					focus = strings.Count(s.String(), "\n")
+					s.WriteString(styles.SelectionItemActive.Render("      > "+option) + "\n")
+				} else {
+					s.WriteString(styles.SelectionItem.Render("        "+option) + "\n")
+				}
+			}
+		}
+	}
+	return s.String(), focus
+}
+
+// categoriesHelp returns the key help of the Skip Categories tab
+func (m Model) categoriesHelp() string {
+	if m.categories.picking {
+		return "enter: Choose action  esc: Cancel"
+	}
+	return "space: Toggle  enter: Pick action"
+}
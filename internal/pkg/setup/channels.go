@@ -0,0 +1,221 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/types"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// channelsTab holds the state of the Channel Whitelist tab
+type channelsTab struct {
+	// cursor is the selected whitelist entry
+	cursor int
+
+	// searching is set while the search box has focus
+	searching bool
+	input     textinput.Model
+	busy      bool
+	spinner   spinner.Model
+
+	// results holds the channels of the last search
+	results      []api.ChannelSearchResult
+	resultCursor int
+}
+
+// channelSearchMsg carries the result of a channel search
+type channelSearchMsg struct {
+	results []api.ChannelSearchResult
+	err     error
+}
+
+func newChannelsTab() channelsTab {
+	input := textinput.New()
+	input.Placeholder = "Channel name or @handle"
+	input.Width = 40
+
+	return channelsTab{
+		input:   input,
+		spinner: newSpinner(),
+	}
+}
+
+// runChannelSearch runs a channel search in the background
+func (m Model) runChannelSearch(query string) tea.Cmd {
+	helper := m.api
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+
+		results, err := helper.SearchChannels(ctx, query)
+		return channelSearchMsg{results: results, err: err}
+	}
+}
+
+// handleChannelSearch lists the channels found by a search
+func (m Model) handleChannelSearch(msg channelSearchMsg) Model {
+	c := &m.channels
+	c.busy = false
+	if msg.err != nil {
+		m.setStatus("Channel search failed: "+msg.err.Error(), true)
+		return m
+	}
+	if len(msg.results) == 0 {
+		m.setStatus("No channels found", true)
+		return m
+	}
+	c.results = msg.results
+	c.resultCursor = 0
+	m.setStatus("", false)
+	return m
+}
+
+// updateChannelsTab handles the keys of the Channel Whitelist tab
+func (m Model) updateChannelsTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	c := &m.channels
+
+	// Search box
+	if c.searching {
+		switch msg.String() {
+		case "esc":
+			c.searching = false
+			c.input.Blur()
+			return m, nil
+		case "enter":
+			query := strings.TrimSpace(c.input.Value())
+			if query == "" || c.busy {
+				return m, nil
+			}
+			if m.config.APIKey == "" {
+				m.setStatus("Searching channels needs a YouTube API key, set one in the YouTube API Key tab", true)
+				return m, nil
+			}
+			c.searching = false
+			c.input.Blur()
+			c.busy = true
+			return m, tea.Batch(c.spinner.Tick, m.runChannelSearch(query))
+		}
+		var cmd tea.Cmd
+		c.input, cmd = c.input.Update(msg)
+		return m, cmd
+	}
+
+	// Search results
+	if len(c.results) > 0 {
+		switch msg.String() {
+		case "up", "k":
+			if c.resultCursor > 0 {
+				c.resultCursor--
+			}
+		case "down", "j":
+			if c.resultCursor < len(c.results)-1 {
+				c.resultCursor++
+			}
+		case "enter":
+			result := c.results[c.resultCursor]
+			if m.config.AddChannel(types.ChannelInfo{ID: result.ID, Name: result.Title}) {
+				m.setStatus(fmt.Sprintf("Added %s to the whitelist", result.Title), false)
+				c.cursor = len(m.config.ChannelWhitelist) - 1
+				c.input.SetValue("")
+			} else {
+				m.setStatus(fmt.Sprintf("%s is already whitelisted", result.Title), true)
+			}
+			c.results = nil
+		case "esc":
+			c.results = nil
+		}
+		return m, nil
+	}
+
+	// Whitelist
+	last := len(m.config.ChannelWhitelist) - 1
+	switch msg.String() {
+	case "/":
+		c.searching = true
+		return m, c.input.Focus()
+	case "up", "k":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+	case "down", "j":
+		if c.cursor < last {
+			c.cursor++
+		}
+	case "K", "shift+up":
+		c.cursor = m.config.MoveChannel(c.cursor, -1)
+	case "J", "shift+down":
+		c.cursor = m.config.MoveChannel(c.cursor, 1)
+	case "x", "delete":
+		if c.cursor <= last {
+			channel := m.config.ChannelWhitelist[c.cursor]
+			m.config.RemoveChannel(channel.ID)
+			m.setStatus(fmt.Sprintf("Removed %s from the whitelist", channelName(channel)), false)
+			if c.cursor > 0 && c.cursor >= len(m.config.ChannelWhitelist) {
+				c.cursor--
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m Model) renderChannelWhitelistTab() string {
+	var s strings.Builder
+	s.WriteString(styles.Title.Render("Channel Whitelist") + "\n")
+	s.WriteString(styles.Subtitle.Render("Segments are not skipped on whitelisted channels") + "\n\n")
+
+	c := m.channels
+	if len(m.config.ChannelWhitelist) == 0 {
+		s.WriteString(styles.Subtitle.Render("No channels whitelisted") + "\n")
+	} else {
+		for i, channel := range m.config.ChannelWhitelist {
+			line := fmt.Sprintf("%s  %s", channelName(channel), channel.ID)
+			if i == c.cursor && len(c.results) == 0 && !c.searching {
+				s.WriteString(styles.SelectionItemActive.Render("> "+line) + "\n")
+			} else {
+				s.WriteString(styles.SelectionItem.Render("  "+line) + "\n")
+			}
+		}
+	}
+
+	s.WriteString(styles.Input.Render(c.input.View()) + "\n")
+	switch {
+	case c.busy:
+		s.WriteString(styles.SelectionItem.Render(c.spinner.View() + " Searching..."))
+	case len(c.results) > 0:
+		for i, result := range c.results {
+			line := fmt.Sprintf("%-30s %-26s %s subscribers", result.Title, result.ID, result.SubscriberCount)
+			if i == c.resultCursor {
+				s.WriteString(styles.SelectionItemActive.Render("> "+line) + "\n")
+			} else {
+				s.WriteString(styles.SelectionItem.Render("  "+line) + "\n")
+			}
+		}
+	}
+	return s.String()
+}
+
+// channelsHelp returns the key help of the Channel Whitelist tab
+func (m Model) channelsHelp() string {
+	switch {
+	case m.channels.searching:
+		return "enter: Search  esc: Cancel"
+	case len(m.channels.results) > 0:
+		return "enter: Add channel  esc: Close results"
+	}
+	return "/: Search  x: Remove  K/J: Move up/down"
+}
+
+// channelName returns the name of a whitelisted channel, or its ID for
+// entries saved without a name
+func channelName(channel types.ChannelInfo) string {
+	if channel.Name == "" {
+		return channel.ID
+	}
+	return channel.Name
+}
@@ -0,0 +1,384 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/dial"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/ytlounge"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// discoveryTimeout bounds a single discovery run
+const discoveryTimeout = 15 * time.Second
+
+// devicesTab holds the state of the Devices tab
+type devicesTab struct {
+	discovering bool
+	spinner     spinner.Model
+	// found holds the TVs of the last discovery run
+	found    []foundDevice
+	selected map[string]bool
+	cursor   int
+
+	// deviceCursor is the selected configured device
+	deviceCursor int
+	calibration  calibration
+
+	// pairing is set while the TV code input is open
+	pairing   bool
+	pairBusy  bool
+	pairInput textinput.Model
+	pairErr   string
+}
+
+// foundDevice is a TV found by discovery
+type foundDevice struct {
+	dial.Device
+	// configured is set for TVs that are already in the config
+	configured bool
+}
+
+// discoveryMsg carries the result of a discovery run
+type discoveryMsg struct {
+	devices []dial.Device
+	err     error
+}
+
+// pairMsg carries the result of pairing with a TV code
+type pairMsg struct {
+	screen *ytlounge.Screen
+	err    error
+}
+
+func newDevicesTab() devicesTab {
+	pairInput := textinput.New()
+	pairInput.Placeholder = "123 456 789 012"
+	pairInput.CharLimit = 20
+	pairInput.Width = 20
+
+	return devicesTab{
+		spinner:   newSpinner(),
+		selected:  make(map[string]bool),
+		pairInput: pairInput,
+	}
+}
+
+// busy reports whether the tab waits for discovery, pairing or calibration
+func (d devicesTab) busy() bool {
+	return d.discovering || d.pairBusy || d.calibration.active && !d.calibration.done
+}
+
+// discoverDevices runs DIAL discovery in the background
+func (m Model) discoverDevices() tea.Cmd {
+	helper := m.api
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+
+		devices, err := DiscoverDevices(ctx, helper)
+		return discoveryMsg{devices: devices, err: err}
+	}
+}
+
+// handleDiscovery lists the TVs found by a discovery run
+func (m Model) handleDiscovery(msg discoveryMsg) Model {
+	m.devices.discovering = false
+	if msg.err != nil {
+		m.setStatus("Discovery failed: "+msg.err.Error(), true)
+		return m
+	}
+
+	m.devices.found = m.devices.found[:0]
+	m.devices.selected = make(map[string]bool)
+	m.devices.cursor = 0
+	for _, device := range msg.devices {
+		m.devices.found = append(m.devices.found, foundDevice{
+			Device:     device,
+			configured: m.config.HasDevice(device.ScreenID),
+		})
+	}
+
+	if len(m.devices.found) == 0 {
+		m.setStatus("No TVs found. Make sure the YouTube app is open on the TV and on the same network", true)
+	} else {
+		m.setStatus(fmt.Sprintf("Found %d TV(s)", len(m.devices.found)), false)
+	}
+	return m
+}
+
+// pairDevice resolves a TV code in the background
+func (m Model) pairDevice(code string) tea.Cmd {
+	httpClient := m.httpClient
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+
+		screen, err := ytlounge.Pair(ctx, httpClient, ytlounge.PairingURL, code)
+		return pairMsg{screen: screen, err: err}
+	}
+}
+
+// handlePair adds the TV resolved from a TV code and stores its lounge token
+func (m Model) handlePair(msg pairMsg) Model {
+	d := &m.devices
+	d.pairBusy = false
+	switch {
+	case errors.Is(msg.err, ytlounge.ErrPairingCodeRejected):
+		d.pairErr = "Wrong or expired code. Get a new code from the TV under Settings > Link with TV code"
+		return m
+	case msg.err != nil:
+		d.pairErr = "Pairing failed: " + msg.err.Error()
+		return m
+	}
+
+	d.pairing = false
+	d.pairErr = ""
+	d.pairInput.Blur()
+
+	screen := msg.screen
+	name := screen.Name
+	if name == "" {
+		name = screen.ScreenID
+	}
+	if screen.LoungeToken != "" {
+		m.loungeTokens[screen.ScreenID] = screen.LoungeToken
+	}
+	if !AddScreen(m.config, screen) {
+		m.setStatus(fmt.Sprintf("%s (%s) is already added", name, screen.ScreenID), true)
+		return m
+	}
+	m.setStatus(fmt.Sprintf("Paired with %s (%s)", name, screen.ScreenID), false)
+	return m
+}
+
+// updatePairInput handles the keys of the TV code input
+func (m Model) updatePairInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	d := &m.devices
+	switch msg.String() {
+	case "esc":
+		d.pairing = false
+		d.pairBusy = false
+		d.pairErr = ""
+		d.pairInput.Blur()
+		return m, nil
+	case "enter":
+		if d.pairBusy {
+			return m, nil
+		}
+		code, err := ytlounge.ParsePairingCode(d.pairInput.Value())
+		if err != nil {
+			d.pairErr = "Enter the 12 digits shown under Settings > Link with TV code"
+			return m, nil
+		}
+		d.pairErr = ""
+		d.pairBusy = true
+		return m, tea.Batch(d.spinner.Tick, m.pairDevice(code))
+	}
+
+	if d.pairBusy {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	d.pairInput, cmd = d.pairInput.Update(msg)
+	return m, cmd
+}
+
+// updateDevicesTab handles the keys of the Devices tab
+func (m Model) updateDevicesTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	d := &m.devices
+	if d.pairing {
+		return m.updatePairInput(msg)
+	}
+	if d.calibration.active {
+		return m.updateCalibration(msg)
+	}
+
+	// Without search results the cursor selects configured devices
+	if len(d.found) == 0 {
+		switch msg.String() {
+		case "up", "k":
+			if d.deviceCursor > 0 {
+				d.deviceCursor--
+			}
+			return m, nil
+		case "down", "j":
+			if d.deviceCursor < len(m.config.Devices)-1 {
+				d.deviceCursor++
+			}
+			return m, nil
+		case "c":
+			if d.deviceCursor < len(m.config.Devices) {
+				return m.startCalibration(m.config.Devices[d.deviceCursor])
+			}
+			return m, nil
+		case "x", "delete":
+			if d.deviceCursor < len(m.config.Devices) {
+				device := m.config.Devices[d.deviceCursor]
+				m.config.RemoveDevice(device.ScreenID)
+				m.setStatus(fmt.Sprintf("Removed %s", device.Name), false)
+				if d.deviceCursor > 0 && d.deviceCursor >= len(m.config.Devices) {
+					d.deviceCursor--
+				}
+			}
+			return m, nil
+		}
+	}
+
+	switch msg.String() {
+	case "p":
+		d.pairing = true
+		d.pairErr = ""
+		d.pairInput.SetValue("")
+		return m, d.pairInput.Focus()
+	case "d":
+		if d.discovering {
+			return m, nil
+		}
+		d.discovering = true
+		m.setStatus("", false)
+		return m, tea.Batch(d.spinner.Tick, m.discoverDevices())
+	case "up", "k":
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case "down", "j":
+		if d.cursor < len(d.found)-1 {
+			d.cursor++
+		}
+	case " ":
+		if d.cursor < len(d.found) && !d.found[d.cursor].configured {
+			screenID := d.found[d.cursor].ScreenID
+			d.selected[screenID] = !d.selected[screenID]
+		}
+	case "enter":
+		m.addSelectedDevices()
+	case "esc":
+		d.found = nil
+		d.selected = make(map[string]bool)
+	}
+	return m, nil
+}
+
+// addSelectedDevices adds the selected TVs to the config
+func (m *Model) addSelectedDevices() {
+	added := 0
+	for i, device := range m.devices.found {
+		if !m.devices.selected[device.ScreenID] {
+			continue
+		}
+		if m.config.AddDevice(device.DeviceConfig()) {
+			added++
+		}
+		m.devices.found[i].configured = true
+	}
+	m.devices.selected = make(map[string]bool)
+
+	if added == 0 {
+		m.setStatus("Select TVs with space before adding them", true)
+		return
+	}
+	m.setStatus(fmt.Sprintf("Added %d device(s)", added), false)
+}
+
+// renderDevicesTab renders the Devices tab and returns the line of the device
+// under the cursor
+func (m Model) renderDevicesTab() (string, int) {
+	var s strings.Builder
+	focus := 0
+	s.WriteString(styles.Title.Render("Devices") + "\n")
+
+	if len(m.config.Devices) == 0 {
+		s.WriteString(styles.Subtitle.Render("No devices added") + "\n")
+	} else {
+		d := m.devices
+		selectable := len(d.found) == 0 && !d.pairing && !d.calibration.active
+		for i, device := range m.config.Devices {
+			name := device.Name
+			if name == "" {
+				name = device.ScreenID
+			}
+			line := fmt.Sprintf("%s  %s  offset %.2f s", name, device.ScreenID, device.Offset)
+			if selectable && i == d.deviceCursor {
+				focus = strings.Count(s.String(), "\n")
+				s.WriteString(styles.SelectionItemActive.Render("> "+line) + "\n")
+			} else {
+				s.WriteString(styles.SelectionItem.Render("  "+line) + "\n")
+			}
+		}
+	}
+
+	d := m.devices
+	switch {
+	case d.calibration.active:
+		s.WriteString("\n" + m.renderCalibration())
+	case d.pairing:
+		s.WriteString("\n" + styles.Subtitle.Render("Enter the code shown on the TV under Settings > Link with TV code") + "\n")
+		s.WriteString(styles.Input.Render(d.pairInput.View()) + "\n")
+		if d.pairBusy {
+			s.WriteString(styles.SelectionItem.Render(d.spinner.View() + " Pairing..."))
+		} else if d.pairErr != "" {
+			s.WriteString(styles.StatusError.Render(d.pairErr))
+		}
+	case d.discovering:
+		s.WriteString("\n" + styles.SelectionItem.Render(d.spinner.View()+" Searching for TVs on the network..."))
+	case len(d.found) > 0:
+		s.WriteString("\n" + styles.Subtitle.Render("Found on the network") + "\n")
+		for i, device := range d.found {
+			checked := " "
+			if d.selected[device.ScreenID] {
+				checked = "x"
+			}
+			line := fmt.Sprintf("[%s] %s  %s", checked, foundName(device.Device), device.ScreenID)
+			if device.configured {
+				line = fmt.Sprintf("[-] %s  %s  (already added)", foundName(device.Device), device.ScreenID)
+			}
+			if i == d.cursor {
+				focus = strings.Count(s.String(), "\n")
+				s.WriteString(styles.SelectionItemActive.Render("> "+line) + "\n")
+			} else {
+				s.WriteString(styles.SelectionItem.Render("  "+line) + "\n")
+			}
+		}
+	default:
+		s.WriteString("\n" + lipgloss.JoinHorizontal(lipgloss.Top,
+			styles.Button.Render("d: Discover TVs"), " ", styles.Button.Render("p: Pair with TV code")))
+	}
+	return s.String(), focus
+}
+
+// foundName describes a TV found by discovery by its name and model
+func foundName(device dial.Device) string {
+	model := device.Model()
+	switch {
+	case device.Name == "":
+		return model
+	case model == "" || model == device.Name:
+		return device.Name
+	default:
+		return fmt.Sprintf("%s (%s)", device.Name, model)
+	}
+}
+
+// devicesHelp returns the key help of the Devices tab
+func (m Model) devicesHelp() string {
+	switch {
+	case m.devices.calibration.active:
+		return m.calibrationHelp()
+	case m.devices.pairing:
+		return "enter: Pair  esc: Cancel"
+	case len(m.devices.found) > 0:
+		return "space: Select  enter: Add selected  esc: Clear  d: Search again  p: Pair"
+	}
+	if len(m.config.Devices) > 0 {
+		return "d: Discover TVs  p: Pair with TV code  c: Calibrate offset  x: Remove"
+	}
+	return "d: Discover TVs  p: Pair with TV code"
+}
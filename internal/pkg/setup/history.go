@@ -0,0 +1,245 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyLimit is the number of changes that can be undone
+const historyLimit = 100
+
+// history holds the undo and redo stacks of the working copy. Every entry is
+// a snapshot of the working copy owned by the stack.
+type history struct {
+	undo []*config.Config
+	redo []*config.Config
+}
+
+// review holds the changes shown before saving
+type review struct {
+	active  bool
+	changes []config.Change
+	// offset is the first change shown, moved with the arrow keys when the
+	// changes do not fit on the terminal
+	offset int
+}
+
+// record pushes the state of the working copy before a key or message was
+// handled onto the undo stack, if the working copy changed
+func (m Model) record(before *config.Config) Model {
+	if len(config.Diff(before, m.config)) == 0 {
+		return m
+	}
+	m.history.undo = append(m.history.undo, before)
+	if len(m.history.undo) > historyLimit {
+		m.history.undo = m.history.undo[1:]
+	}
+	m.history.redo = nil
+	return m
+}
+
+// undo reverts the last change to the working copy
+func (m Model) undo() Model {
+	n := len(m.history.undo)
+	if n == 0 {
+		m.setStatus("Nothing to undo", true)
+		return m
+	}
+	snapshot := m.history.undo[n-1]
+	m.history.undo = m.history.undo[:n-1]
+	m.history.redo = append(m.history.redo, m.config.Clone())
+	m.setStatus("Undid "+describeChanges(config.Diff(snapshot, m.config)), false)
+	m.restore(snapshot)
+	return m
+}
+
+// redo reapplies the last change reverted by undo
+func (m Model) redo() Model {
+	n := len(m.history.redo)
+	if n == 0 {
+		m.setStatus("Nothing to redo", true)
+		return m
+	}
+	snapshot := m.history.redo[n-1]
+	m.history.redo = m.history.redo[:n-1]
+	m.history.undo = append(m.history.undo, m.config.Clone())
+	m.setStatus("Redid "+describeChanges(config.Diff(m.config, snapshot)), false)
+	m.restore(snapshot)
+	return m
+}
+
+// restore replaces the working copy with a snapshot and brings the tabs in
+// line with it. The config is replaced in place because the API helper holds
+// a pointer to it.
+func (m *Model) restore(snapshot *config.Config) {
+	*m.config = *snapshot
+
+	cursor := m.categories.cursor
+	m.categories = newCategoriesTab(m.config)
+	m.categories.cursor = cursor
+
+	for i := range m.devices.found {
+		m.devices.found[i].configured = m.config.HasDevice(m.devices.found[i].ScreenID)
+	}
+	if m.devices.deviceCursor >= len(m.config.Devices) {
+		m.devices.deviceCursor = max(len(m.config.Devices)-1, 0)
+	}
+	if m.channels.cursor >= len(m.config.ChannelWhitelist) {
+		m.channels.cursor = max(len(m.config.ChannelWhitelist)-1, 0)
+	}
+}
+
+// describeChanges names the settings changed, e.g. for the status line
+func describeChanges(changes []config.Change) string {
+	switch len(changes) {
+	case 0:
+		return "nothing"
+	case 1:
+		return changes[0].Path
+	default:
+		return fmt.Sprintf("%d settings", len(changes))
+	}
+}
+
+// modified reports whether the working copy differs from the config as it
+// was last loaded or saved
+func (m Model) modified() bool {
+	return len(config.Diff(m.saved, m.config)) > 0
+}
+
+// onDisk loads the config file as it is now. A missing file counts as the
+// defaults; a file that can no longer be read counts as it was last loaded
+// or saved.
+func (m Model) onDisk() *config.Config {
+	cfg, err := config.LoadConfig(m.config.ConfigFile, m.config.DataDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg, err = config.NewConfig(m.config.ConfigFile, m.config.DataDir)
+	}
+	if err != nil {
+		return m.saved
+	}
+	return cfg
+}
+
+// startReview shows the changes against the config file before saving
+func (m Model) startReview() Model {
+	changes := config.Diff(m.onDisk(), m.config)
+	if len(changes) == 0 {
+		m.setStatus("No changes to save", false)
+		return m
+	}
+	m.review = review{active: true, changes: changes}
+	m.setStatus("", false)
+	return m
+}
+
+// updateReview handles the keys of the review shown before saving
+func (m Model) updateReview(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter", "y", "s":
+		m.review = review{}
+		if err := config.Save(m.config); err != nil {
+			m.setStatus("Failed to save config: "+err.Error(), true)
+			return m, nil
+		}
+		err := m.saveLoungeTokens()
+		m.saved = m.config.Clone()
+		if err != nil {
+			m.setStatus("Config saved, but "+err.Error(), true)
+			return m, nil
+		}
+		m.setStatus("Config saved to "+m.config.ConfigFile, false)
+	case "esc", "n":
+		m.review = review{}
+	case "up", "k":
+		if m.review.offset > 0 {
+			m.review.offset--
+		}
+	case "down", "j":
+		if m.review.offset < len(m.review.changes)-1 {
+			m.review.offset++
+		}
+	}
+	return m, nil
+}
+
+// saveLoungeTokens brings the lounge tokens in line with the config just
+// saved: the tokens of paired TVs that were saved are stored and those of
+// saved TVs that were removed are deleted. Tokens of TVs whose pairing was
+// undone are kept in case it is redone.
+func (m *Model) saveLoungeTokens() error {
+	var errs []error
+	for screenID, token := range m.loungeTokens {
+		if !m.config.HasDevice(screenID) {
+			continue
+		}
+		if err := StoreLoungeToken(m.config, screenID, token); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(m.loungeTokens, screenID)
+	}
+	for _, device := range m.saved.Devices {
+		if m.config.HasDevice(device.ScreenID) {
+			continue
+		}
+		if err := DeleteLoungeToken(m.config, device.ScreenID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// renderReview renders the changes against the config file, removed values
+// in red and added values in green
+func (m Model) renderReview() string {
+	var s strings.Builder
+	s.WriteString(styles.Title.Render("Review changes") + "\n")
+	s.WriteString(styles.Subtitle.Render("Changes to "+m.config.ConfigFile) + "\n\n")
+
+	if m.review.offset > 0 {
+		s.WriteString(styles.Subtitle.Render(fmt.Sprintf("... %d more above", m.review.offset)) + "\n")
+	}
+	for _, change := range m.review.changes[m.review.offset:] {
+		if change.Old != "" {
+			s.WriteString(styles.DiffRemoved.Render(
+				fmt.Sprintf("- %s = %s", change.Path, maskSecret(change.Path, change.Old)),
+			) + "\n")
+		}
+		if change.New != "" {
+			s.WriteString(styles.DiffAdded.Render(
+				fmt.Sprintf("+ %s = %s", change.Path, maskSecret(change.Path, change.New)),
+			) + "\n")
+		}
+	}
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+// maskSecret hides API keys in the review
+func maskSecret(path, value string) string {
+	if strings.HasSuffix(path, "apikey") && value != `""` {
+		return `"********"`
+	}
+	return value
+}
+
+// updateQuitPrompt handles the keys of the unsaved changes warning
+func (m Model) updateQuitPrompt(msg tea.KeyMsg) (Model, tea.Cmd) {
+	m.confirmingQuit = false
+	switch msg.String() {
+	case "q", "y", "ctrl+c":
+		return m, tea.Quit
+	case "s":
+		return m.startReview(), nil
+	}
+	return m, nil
+}
+
+// quitPrompt is the warning shown when quitting with unsaved changes
+const quitPrompt = "You have unsaved changes. q: Quit without saving  s: Review and save  esc: Keep editing"
@@ -1,39 +1,82 @@
 package setup
 
 import (
+	"context"
+	"net/http"
 	"strings"
+	"time"
 
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/constants"
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/styles"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Tab indexes
+const (
+	tabDevices = iota
+	tabSkipCategories
+	tabSkipCountTracking
+	tabAds
+	tabChannelWhitelist
+	tabAPIKey
+	tabAutoplay
+)
+
 // Model represents the main application state
 type Model struct {
+	// config is the working copy edited by the tabs; saved is the config as
+	// it was last loaded or saved
 	config     *config.Config
-	currentTab int
-	tabs       []string
-	width      int
-	height     int
-	// Selection states
-	skipCategories    map[string]bool
-	skipCountTracking bool
-	muteAds           bool
-	skipAds           bool
-	autoplay          bool
+	saved      *config.Config
+	api        *api.APIHelper
+	httpClient *http.Client
+	// checkAPIKey tests API keys entered in the YouTube API Key tab
+	checkAPIKey APIKeyChecker
+	currentTab  int
+	tabs        []string
+	width       int
+	height      int
+	// adsCursor is the selected checkbox of the Skip/Mute ads tab
+	adsCursor int
+	// Tab states
+	devices    devicesTab
+	categories categoriesTab
+	channels   channelsTab
+	apiKey     apiKeyTab
+	// Status line shown above the footer
+	status    string
+	statusErr bool
+
+	// loungeTokens holds the lounge tokens of TVs paired in the wizard by
+	// screen ID. They are stored when the config is saved.
+	loungeTokens map[string]string
+
+	history history
+	review  review
+	// confirmingQuit is set while the unsaved changes warning is shown
+	confirmingQuit bool
 }
 
 // InitialModel creates a new model with default values
 func InitialModel(cfg *config.Config) Model {
-	skipCats := make(map[string]bool)
-	for _, cat := range cfg.SkipCategories {
-		skipCats[cat] = true
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
 	}
 
 	return Model{
-		config:     cfg,
-		currentTab: 0,
+		config:       cfg,
+		saved:        cfg.Clone(),
+		loungeTokens: make(map[string]string),
+		api:          api.NewAPIHelper(cfg, httpClient),
+		httpClient:   httpClient,
+		checkAPIKey: func(ctx context.Context, key string) (api.APIKeyStatus, string, error) {
+			return api.CheckAPIKey(ctx, httpClient, constants.YouTubeAPI, key)
+		},
+		currentTab: tabDevices,
 		tabs: []string{
 			"Devices",
 			"Skip Categories",
@@ -43,11 +86,10 @@ func InitialModel(cfg *config.Config) Model {
 			"YouTube API Key",
 			"Autoplay",
 		},
-		skipCategories:    skipCats,
-		skipCountTracking: cfg.SkipCountTracking,
-		muteAds:           cfg.MuteAds,
-		skipAds:           cfg.SkipAds,
-		autoplay:          cfg.AutoPlay,
+		devices:    newDevicesTab(),
+		categories: newCategoriesTab(cfg),
+		channels:   newChannelsTab(),
+		apiKey:     newAPIKeyTab(),
 	}
 }
 
@@ -60,130 +102,311 @@ func (m Model) Init() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-		case "tab", "right", "l":
-			m.currentTab = (m.currentTab + 1) % len(m.tabs)
-		case "shift+tab", "left", "h":
-			m.currentTab = (m.currentTab - 1 + len(m.tabs)) % len(m.tabs)
-		case "s":
-			m.saveConfig()
-		}
+		return m.updateKey(msg)
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		styles.Resize(msg.Width)
+	case discoveryMsg:
+		m = m.handleDiscovery(msg)
+	case pairMsg:
+		before := m.config.Clone()
+		m = m.handlePair(msg).record(before)
+	case channelSearchMsg:
+		m = m.handleChannelSearch(msg)
+	case apiKeyCheckMsg:
+		m = m.handleAPIKeyCheck(msg)
+	case calibrationStepMsg:
+		return m.handleCalibrationStep(msg)
+	case calibrationDoneMsg:
+		m = m.handleCalibrationDone(msg)
+	case spinner.TickMsg:
+		// Spinners stop ticking once their tab is no longer busy
+		var cmd tea.Cmd
+		switch {
+		case msg.ID == m.devices.spinner.ID() && m.devices.busy():
+			m.devices.spinner, cmd = m.devices.spinner.Update(msg)
+		case msg.ID == m.channels.spinner.ID() && m.channels.busy:
+			m.channels.spinner, cmd = m.channels.spinner.Update(msg)
+		case msg.ID == m.apiKey.spinner.ID() && m.apiKey.busy:
+			m.apiKey.spinner, cmd = m.apiKey.spinner.Update(msg)
+		}
+		return m, cmd
+	default:
+		// Cursor blinking of the focused text input
+		var cmd tea.Cmd
+		switch {
+		case m.devices.pairing:
+			m.devices.pairInput, cmd = m.devices.pairInput.Update(msg)
+		case m.channels.searching:
+			m.channels.input, cmd = m.channels.input.Update(msg)
+		case m.apiKey.editing:
+			m.apiKey.input, cmd = m.apiKey.input.Update(msg)
+		}
+		return m, cmd
+	}
+	return m, nil
+}
+
+// updateKey handles a key press
+func (m Model) updateKey(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case m.confirmingQuit:
+		return m.updateQuitPrompt(msg)
+	case msg.String() == "ctrl+c":
+		return m.quit()
+	case m.review.active:
+		return m.updateReview(msg)
+	case m.capturing():
+		// Text inputs get every key
+		return m.editCurrentTab(msg)
+	}
+
+	switch msg.String() {
+	case "q":
+		return m.quit()
+	case "tab", "right", "l":
+		m.currentTab = (m.currentTab + 1) % len(m.tabs)
+	case "shift+tab", "left", "h":
+		m.currentTab = (m.currentTab - 1 + len(m.tabs)) % len(m.tabs)
+	case "s":
+		m = m.startReview()
+	case "u":
+		m = m.undo()
+	case "ctrl+r":
+		m = m.redo()
+	default:
+		return m.editCurrentTab(msg)
+	}
+	return m, nil
+}
+
+// editCurrentTab passes a key to the current tab and records the change it
+// makes to the working copy for undo
+func (m Model) editCurrentTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	before := m.config.Clone()
+	next, cmd := m.updateCurrentTab(msg)
+	return next.record(before), cmd
+}
+
+// quit exits, or warns first if the working copy has unsaved changes
+func (m Model) quit() (Model, tea.Cmd) {
+	if !m.modified() {
+		return m, tea.Quit
+	}
+	m.confirmingQuit = true
+	return m, nil
+}
+
+// capturing reports whether a text input of the current tab has focus
+func (m Model) capturing() bool {
+	switch m.currentTab {
+	case tabDevices:
+		return m.devices.pairing || m.devices.calibration.active
+	case tabSkipCategories:
+		return m.categories.picking
+	case tabChannelWhitelist:
+		return m.channels.searching
+	case tabAPIKey:
+		return m.apiKey.editing
+	}
+	return false
+}
+
+// updateCurrentTab passes keys not handled globally to the current tab
+func (m Model) updateCurrentTab(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch m.currentTab {
+	case tabDevices:
+		return m.updateDevicesTab(msg)
+	case tabSkipCategories:
+		return m.updateCategoriesTab(msg)
+	case tabChannelWhitelist:
+		return m.updateChannelsTab(msg)
+	case tabAPIKey:
+		return m.updateAPIKeyTab(msg)
+	case tabSkipCountTracking, tabAds, tabAutoplay:
+		return m.updateToggles(msg)
 	}
 	return m, nil
 }
 
-func (m *Model) saveConfig() {
-	m.config.SkipCategories = make([]string, 0)
-	for cat, selected := range m.skipCategories {
-		if selected {
-			m.config.SkipCategories = append(m.config.SkipCategories, cat)
+// updateToggles handles the keys of the tabs made of checkboxes
+func (m Model) updateToggles(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.currentTab == tabAds {
+			m.adsCursor = 0
+		}
+	case "down", "j":
+		if m.currentTab == tabAds {
+			m.adsCursor = 1
+		}
+	case " ", "enter":
+		switch {
+		case m.currentTab == tabSkipCountTracking:
+			m.config.SkipCountTracking = !m.config.SkipCountTracking
+		case m.currentTab == tabAds && m.adsCursor == 0:
+			m.config.SkipAds = !m.config.SkipAds
+		case m.currentTab == tabAds:
+			m.config.MuteAds = !m.config.MuteAds
+		case m.currentTab == tabAutoplay:
+			m.config.AutoPlay = !m.config.AutoPlay
 		}
 	}
-	m.config.SkipCountTracking = m.skipCountTracking
-	m.config.MuteAds = m.muteAds
-	m.config.SkipAds = m.skipAds
-	m.config.AutoPlay = m.autoplay
+	return m, nil
+}
+
+// setStatus sets the status line
+func (m *Model) setStatus(status string, isErr bool) {
+	m.status = status
+	m.statusErr = isErr
 }
 
 // View renders the UI
 func (m Model) View() string {
-	doc := strings.Builder{}
-
 	// Header
-	doc.WriteString(styles.Title.Render("iSponsorBlockTV - Setup Wizard") + "\n\n")
+	header := styles.Title.Copy().Width(m.layoutWidth()).Render("iSponsorBlockTV - Setup Wizard") +
+		"\n\n" + m.renderTabs() + "\n"
 
-	// Tabs
-	tabs := make([]string, len(m.tabs))
-	for i, tab := range m.tabs {
-		if i == m.currentTab {
-			tabs[i] = styles.TabActive.Render(tab)
+	// Status
+	var status string
+	if m.confirmingQuit {
+		status = styles.StatusWarning.Render(quitPrompt) + "\n"
+	} else if m.status != "" {
+		if m.statusErr {
+			status = styles.StatusError.Render(m.status) + "\n"
 		} else {
-			tabs[i] = styles.TabInactive.Render(tab)
+			status = styles.StatusSuccess.Render(m.status) + "\n"
 		}
 	}
-	doc.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, tabs...) + "\n\n")
-
-	// Content
-	content := m.renderCurrentTab()
-	doc.WriteString(styles.Container.Render(content) + "\n")
 
 	// Footer
-	doc.WriteString(styles.Footer.Render("q: Exit  s: Save"))
+	help := "q: Exit  s: Save  u: Undo  ctrl+r: Redo"
+	if m.capturing() {
+		help = "ctrl+c: Exit"
+	}
+	if tabHelp := m.currentTabHelp(); tabHelp != "" {
+		help = tabHelp + "  " + help
+	}
+	if m.review.active {
+		help = "up/down: Scroll  enter: Save  esc: Back"
+	}
+	if m.modified() {
+		help = "[modified]  " + help
+	}
+	footer := styles.Footer.Render(help)
 
-	return doc.String()
+	// Content, cut to the lines left over on short terminals around the
+	// selected line
+	content, focus := m.renderCurrentTab()
+	if m.review.active {
+		content, focus = m.renderReview(), 0
+	}
+	if m.height > 0 {
+		frame := styles.Container.GetVerticalFrameSize() + 1
+		available := m.height - lipgloss.Height(header) - lipgloss.Height(status) - lipgloss.Height(footer) - frame
+		content = clipLines(content, available, focus)
+	}
+
+	return header + styles.Container.Render(content) + "\n" + status + footer
 }
 
-func (m Model) renderCurrentTab() string {
-	switch m.currentTab {
-	case 0:
-		return m.renderDevicesTab()
-	case 1:
-		return m.renderSkipCategoriesTab()
-	case 2:
-		return m.renderSkipCountTrackingTab()
-	case 3:
-		return m.renderAdSkipMuteTab()
-	case 4:
-		return m.renderChannelWhitelistTab()
-	case 5:
-		return m.renderAPIKeyTab()
-	case 6:
-		return m.renderAutoplayTab()
-	default:
-		return ""
+// layoutWidth is the width the wizard is drawn in
+func (m Model) layoutWidth() int {
+	if m.width > 0 {
+		return m.width
 	}
+	return 100
 }
 
-func (m Model) renderDevicesTab() string {
-	var s strings.Builder
-	s.WriteString(styles.Title.Render("Devices") + "\n")
-	s.WriteString(styles.Button.Render("Add Device") + "\n\n")
-
-	if len(m.config.Devices) == 0 {
-		s.WriteString(styles.Subtitle.Render("No devices added"))
-	} else {
-		for _, device := range m.config.Devices {
-			name := device.Name
-			if name == "" {
-				name = device.ScreenID
-			}
-			s.WriteString(styles.SelectionItem.Render(name) + "\n")
+// renderTabs renders the tab bar, wrapped onto more rows when the tabs do not
+// fit next to each other
+func (m Model) renderTabs() string {
+	width := m.layoutWidth()
+	rows := make([]string, 0, 1)
+	row := make([]string, 0, len(m.tabs))
+	for i, tab := range m.tabs {
+		rendered := styles.TabInactive.Render(tab)
+		if i == m.currentTab {
+			rendered = styles.TabActive.Render(tab)
 		}
+		if len(row) > 0 && lipgloss.Width(lipgloss.JoinHorizontal(lipgloss.Top, append(row, rendered)...)) > width {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row = row[:0]
+		}
+		row = append(row, rendered)
 	}
-	return s.String()
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	return strings.Join(rows, "\n")
 }
 
-func (m Model) renderSkipCategoriesTab() string {
-	var s strings.Builder
-	s.WriteString(styles.Title.Render("Skip Categories") + "\n")
-	s.WriteString(styles.Subtitle.Render("Select the categories you want to skip") + "\n\n")
-
-	categories := []string{
-		"Sponsor",
-		"Self-Promotion",
-		"Intro",
-		"Outro",
-		"Interaction",
-		"Music Offtopic",
-		"Preview",
-		"Filler",
+// clipLines cuts text to a window of at most n lines centred on the focus
+// line, marking the ends where lines were left out
+func clipLines(text string, n, focus int) string {
+	lines := strings.Split(text, "\n")
+	if n < 1 || len(lines) <= n {
+		return text
 	}
 
-	for _, cat := range categories {
-		checked := " "
-		if m.skipCategories[cat] {
-			checked = "x"
-		}
-		s.WriteString(styles.SelectionItem.Render(
-			lipgloss.JoinHorizontal(lipgloss.Left, "["+checked+"]", " "+cat),
-		) + "\n")
+	start := min(max(focus-n/2, 0), len(lines)-n)
+	end := start + n
+	window := append([]string(nil), lines[start:end]...)
+	if start > 0 {
+		window[0] = styles.Subtitle.Render("...")
+	}
+	if end < len(lines) {
+		window[n-1] = styles.Subtitle.Render("...")
+	}
+	return strings.Join(window, "\n")
+}
+
+// newSpinner returns the spinner of busy tabs, drawn in ASCII when boxes are
+func newSpinner() spinner.Model {
+	if styles.ASCII() {
+		return spinner.New(spinner.WithSpinner(spinner.Line))
+	}
+	return spinner.New(spinner.WithSpinner(spinner.Dot))
+}
+
+// renderCurrentTab renders the current tab and returns the line of its
+// selection, which is kept in view on short terminals
+func (m Model) renderCurrentTab() (string, int) {
+	switch m.currentTab {
+	case tabDevices:
+		return m.renderDevicesTab()
+	case tabSkipCategories:
+		return m.renderSkipCategoriesTab()
+	case tabSkipCountTracking:
+		return m.renderSkipCountTrackingTab(), 0
+	case tabAds:
+		return m.renderAdSkipMuteTab(), 0
+	case tabChannelWhitelist:
+		return m.renderChannelWhitelistTab(), 0
+	case tabAPIKey:
+		return m.renderAPIKeyTab(), 0
+	case tabAutoplay:
+		return m.renderAutoplayTab(), 0
+	default:
+		return "", 0
+	}
+}
+
+// currentTabHelp returns the key help of the current tab
+func (m Model) currentTabHelp() string {
+	switch m.currentTab {
+	case tabDevices:
+		return m.devicesHelp()
+	case tabSkipCategories:
+		return m.categoriesHelp()
+	case tabChannelWhitelist:
+		return m.channelsHelp()
+	case tabAPIKey:
+		return m.apiKeyHelp()
+	case tabSkipCountTracking, tabAds, tabAutoplay:
+		return "space: Toggle"
+	default:
+		return ""
 	}
-	return s.String()
 }
 
 func (m Model) renderSkipCountTrackingTab() string {
@@ -194,7 +417,7 @@ func (m Model) renderSkipCountTrackingTab() string {
 	) + "\n\n")
 
 	checked := " "
-	if m.skipCountTracking {
+	if m.config.SkipCountTracking {
 		checked = "x"
 	}
 	s.WriteString(styles.Checkbox.Render(
@@ -211,53 +434,29 @@ func (m Model) renderAdSkipMuteTab() string {
 	) + "\n\n")
 
 	skipChecked := " "
-	if m.skipAds {
+	if m.config.SkipAds {
 		skipChecked = "x"
 	}
 	muteChecked := " "
-	if m.muteAds {
+	if m.config.MuteAds {
 		muteChecked = "x"
 	}
 
-	s.WriteString(styles.Checkbox.Render(
+	skipStyle, muteStyle := styles.Checkbox, styles.Checkbox
+	if m.adsCursor == 0 {
+		skipStyle = skipStyle.Copy().Inherit(styles.CheckboxChecked)
+	} else {
+		muteStyle = muteStyle.Copy().Inherit(styles.CheckboxChecked)
+	}
+	s.WriteString(skipStyle.Render(
 		lipgloss.JoinHorizontal(lipgloss.Left, "["+skipChecked+"]", " Enable skipping ads"),
 	) + "\n")
-	s.WriteString(styles.Checkbox.Render(
+	s.WriteString(muteStyle.Render(
 		lipgloss.JoinHorizontal(lipgloss.Left, "["+muteChecked+"]", " Enable muting ads"),
 	))
 	return s.String()
 }
 
-func (m Model) renderChannelWhitelistTab() string {
-	var s strings.Builder
-	s.WriteString(styles.Title.Render("Channel Whitelist") + "\n")
-	s.WriteString(styles.Button.Render("Add Channel") + "\n\n")
-
-	if len(m.config.ChannelWhitelist) == 0 {
-		s.WriteString(styles.Subtitle.Render("No channels whitelisted"))
-	} else {
-		for _, channel := range m.config.ChannelWhitelist {
-			s.WriteString(styles.SelectionItem.Render(channel.ID) + "\n")
-		}
-	}
-	return s.String()
-}
-
-func (m Model) renderAPIKeyTab() string {
-	var s strings.Builder
-	s.WriteString(styles.Title.Render("YouTube API Key") + "\n")
-	s.WriteString(styles.Subtitle.Render(
-		"You can get a YouTube Data API v3 Key from the Google Cloud Console",
-	) + "\n\n")
-
-	key := m.config.APIKey
-	if key == "" {
-		key = "Enter your API key"
-	}
-	s.WriteString(styles.Input.Render(key))
-	return s.String()
-}
-
 func (m Model) renderAutoplayTab() string {
 	var s strings.Builder
 	s.WriteString(styles.Title.Render("Autoplay") + "\n")
@@ -266,7 +465,7 @@ func (m Model) renderAutoplayTab() string {
 	) + "\n\n")
 
 	checked := " "
-	if m.autoplay {
+	if m.config.AutoPlay {
 		checked = "x"
 	}
 	s.WriteString(styles.Checkbox.Render(
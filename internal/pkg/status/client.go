@@ -0,0 +1,134 @@
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// requestTimeout bounds a single request to the status server
+const requestTimeout = 5 * time.Second
+
+// maxResponseSize bounds a single response line
+const maxResponseSize = 4 * 1024 * 1024
+
+// Client talks to the status server of a running daemon. A connection that
+// broke, e.g. because the daemon restarted, is dialed again on the next
+// request.
+type Client struct {
+	path    string
+	mu      sync.Mutex
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+// Dial connects to the status socket at path
+func Dial(path string) (*Client, error) {
+	c := &Client{path: path}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connect dials the status socket. c.mu must be held unless c is new.
+func (c *Client) connect() error {
+	conn, err := net.DialTimeout("unix", c.path, requestTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the daemon at %s, is it running? %w", c.path, err)
+	}
+	// A snapshot carries up to the whole log
+	c.scanner = bufio.NewScanner(conn)
+	c.scanner.Buffer(make([]byte, 64*1024), maxResponseSize)
+	c.conn = conn
+	return nil
+}
+
+// disconnect drops a broken connection. c.mu must be held.
+func (c *Client) disconnect() {
+	c.conn.Close()
+	c.conn = nil
+}
+
+// Status returns the state of the daemon with the log entries after the
+// given sequence number
+func (c *Client) Status(logSince uint64) (*Snapshot, error) {
+	response, err := c.do(Request{Command: CommandStatus, LogSince: logSince})
+	if err != nil {
+		return nil, err
+	}
+	if response.Snapshot == nil {
+		return nil, errors.New("the daemon sent no status")
+	}
+	return response.Snapshot, nil
+}
+
+// SetSkippingPaused pauses or resumes skipping on a device
+func (c *Client) SetSkippingPaused(screenID string, paused bool) error {
+	_, err := c.do(Request{Command: CommandPause, ScreenID: screenID, Paused: paused})
+	return err
+}
+
+// Skip skips the next segment of a device right away
+func (c *Client) Skip(screenID string) error {
+	_, err := c.do(Request{Command: CommandSkip, ScreenID: screenID})
+	return err
+}
+
+// do sends a request and waits for its response
+func (c *Client) do(request Request) (Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return Response{}, err
+	}
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return Response{}, err
+		}
+	}
+	if err := c.conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		c.disconnect()
+		return Response{}, err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		c.disconnect()
+		return Response{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	if !c.scanner.Scan() {
+		err := c.scanner.Err()
+		c.disconnect()
+		if err != nil {
+			return Response{}, fmt.Errorf("failed to read response: %w", err)
+		}
+		return Response{}, errors.New("the daemon closed the connection")
+	}
+
+	var response Response
+	if err := json.Unmarshal(c.scanner.Bytes(), &response); err != nil {
+		return Response{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
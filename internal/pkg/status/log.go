@@ -0,0 +1,106 @@
+package status
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logSize is the number of log entries kept for the dashboard
+const logSize = 500
+
+// LogEntry is a log line of the daemon
+type LogEntry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Device  string    `json:"device,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Log keeps the most recent log entries of the daemon
+type Log struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	seq     uint64
+}
+
+// NewLog creates an empty log
+func NewLog() *Log {
+	return &Log{entries: make([]LogEntry, 0, logSize)}
+}
+
+// Add appends an entry, dropping the oldest one when the log is full
+func (l *Log) Add(level, device, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	if len(l.entries) == logSize {
+		l.entries = append(l.entries[:0], l.entries[1:]...)
+	}
+	l.entries = append(l.entries, LogEntry{
+		Seq:     l.seq,
+		Time:    time.Now(),
+		Level:   level,
+		Device:  device,
+		Message: message,
+	})
+}
+
+// Since returns the entries after the given sequence number
+func (l *Log) Since(seq uint64) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := make([]LogEntry, 0)
+	for _, entry := range l.entries {
+		if entry.Seq > seq {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// stdLogTimeLayout is the date and time prefix of the standard logger
+const stdLogTimeLayout = "2006/01/02 15:04:05 "
+
+// Write adds every line written as an info entry, so the log can be the
+// output of a standard library logger. The date and time the standard
+// logger prefixes are dropped since entries carry their own.
+func (l *Log) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if len(line) >= len(stdLogTimeLayout) {
+			if _, err := time.Parse(stdLogTimeLayout, line[:len(stdLogTimeLayout)]); err == nil {
+				line = line[len(stdLogTimeLayout):]
+			}
+		}
+		if line != "" {
+			l.Add(logrus.InfoLevel.String(), "", line)
+		}
+	}
+	return len(p), nil
+}
+
+// Hook returns a logrus hook that adds the entries of a device logger to the
+// log
+func (l *Log) Hook(device string) logrus.Hook {
+	return &logHook{log: l, device: device}
+}
+
+// logHook adds logrus entries to a Log
+type logHook struct {
+	log    *Log
+	device string
+}
+
+func (h *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logHook) Fire(entry *logrus.Entry) error {
+	h.log.Add(entry.Level.String(), h.device, entry.Message)
+	return nil
+}
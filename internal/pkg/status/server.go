@@ -0,0 +1,129 @@
+package status
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Serve answers status requests on a Unix socket at path until ctx is done.
+// Every line a client sends is a JSON Request and is answered with a JSON
+// Response line. A socket left behind by a daemon that is no longer running
+// is replaced. The directory of path is created if needed and restricted to
+// the current user, so the socket cannot be reached by other users before
+// its own permissions are set.
+func Serve(ctx context.Context, path string, provider Provider, log *Log) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	// MkdirAll leaves the mode of an existing directory alone
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to restrict %s: %w", dir, err)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Only the user running the daemon may control it
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict %s: %w", path, err)
+	}
+
+	startedAt := time.Now()
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept status connection: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(ctx, conn, provider, log, startedAt)
+		}()
+	}
+}
+
+// serveConn answers the requests of a single client
+func serveConn(ctx context.Context, conn net.Conn, provider Provider, log *Log, startedAt time.Time) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var request Request
+		var response Response
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = "invalid request: " + err.Error()
+		} else {
+			response = handle(request, provider, log, startedAt)
+		}
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+	}
+}
+
+// handle runs a single request
+func handle(request Request, provider Provider, log *Log, startedAt time.Time) Response {
+	var err error
+	switch request.Command {
+	case CommandStatus:
+		return Response{Snapshot: &Snapshot{
+			StartedAt: startedAt,
+			Devices:   provider.Devices(),
+			Log:       log.Since(request.LogSince),
+		}}
+	case CommandPause:
+		err = provider.SetSkippingPaused(request.ScreenID, request.Paused)
+	case CommandSkip:
+		err = provider.Skip(request.ScreenID)
+	default:
+		err = fmt.Errorf("unknown command %q", request.Command)
+	}
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return Response{}
+}
+
+// removeStaleSocket removes a socket at path that no daemon listens on
+func removeStaleSocket(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("another daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
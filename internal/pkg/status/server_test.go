@@ -0,0 +1,67 @@
+package status
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// idleProvider is a Provider without devices
+type idleProvider struct{}
+
+func (idleProvider) Devices() []DeviceStatus              { return nil }
+func (idleProvider) SetSkippingPaused(string, bool) error { return ErrUnknownDevice }
+func (idleProvider) Skip(string) error                    { return ErrUnknownDevice }
+
+// waitFor polls cond until it holds or a second passed
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return true
+		}
+	}
+	return cond()
+}
+
+func TestServe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SocketFileName)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, path, idleProvider{}, NewLog()) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}()
+
+	if !waitFor(func() bool { _, err := os.Stat(path); return err == nil }) {
+		t.Fatal("socket was not created")
+	}
+	info, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("socket directory mode = %o, want 700", perm)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 3; i++ {
+		client, err := Dial(path)
+		if err != nil {
+			t.Fatalf("Dial: %v", err)
+		}
+		if _, err := client.Status(0); err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		client.Close()
+	}
+	// Closed connections leave no goroutine behind
+	if !waitFor(func() bool { return runtime.NumGoroutine() <= before }) {
+		t.Errorf("%d goroutines after the clients closed, want at most %d", runtime.NumGoroutine(), before)
+	}
+}
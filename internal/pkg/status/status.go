@@ -0,0 +1,124 @@
+package status
+
+import (
+	"errors"
+	"time"
+)
+
+// SocketFileName is the path of the status socket in the data directory. The
+// socket has a directory of its own, which Serve restricts to the user
+// running the daemon.
+const SocketFileName = "control/status.sock"
+
+// Playback states of a device
+const (
+	StateDisconnected = "disconnected"
+	StateIdle         = "idle"
+	StatePlaying      = "playing"
+	StatePaused       = "paused"
+	StateBuffering    = "buffering"
+)
+
+// Ad states of a device
+const (
+	AdNone    = ""
+	AdPlaying = "playing"
+	AdMuted   = "muted"
+	AdSkipped = "skipped"
+)
+
+// Network states of a device, as seen by discovery on the local network
+const (
+	NetworkUnknown = ""
+	NetworkOnline  = "online"
+	NetworkOffline = "offline"
+)
+
+// Commands understood by the status server
+const (
+	CommandStatus = "status"
+	CommandPause  = "pause"
+	CommandSkip   = "skip"
+)
+
+var (
+	// ErrUnknownDevice is returned for screen IDs the daemon does not run
+	ErrUnknownDevice = errors.New("unknown device")
+	// ErrNoSegment is returned when a skip is forced without a segment ahead
+	ErrNoSegment = errors.New("no segment ahead to skip")
+)
+
+// DeviceStatus is the state of a device as reported by the daemon
+type DeviceStatus struct {
+	ScreenID string `json:"screen_id"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	VideoID  string `json:"video_id,omitempty"`
+	// Position is the playback position in seconds at UpdatedAt
+	Position  float64   `json:"position"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// NextSegment is the segment the daemon waits for, if any
+	NextSegment *SegmentStatus `json:"next_segment,omitempty"`
+	Ad          string         `json:"ad,omitempty"`
+	// SkippingPaused is set while skipping is paused on the device
+	SkippingPaused bool `json:"skipping_paused"`
+	// Network is whether the device was last seen on the local network and
+	// Address its IP address there
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+	// Schedules lists the schedules in effect on the device, each followed
+	// by the profile it applies in parentheses
+	Schedules []string `json:"schedules,omitempty"`
+}
+
+// CurrentPosition returns the playback position at now, advanced from
+// Position while the device plays
+func (d DeviceStatus) CurrentPosition(now time.Time) float64 {
+	if d.State != StatePlaying || d.UpdatedAt.IsZero() {
+		return d.Position
+	}
+	return d.Position + now.Sub(d.UpdatedAt).Seconds()
+}
+
+// SegmentStatus describes the next segment of a device
+type SegmentStatus struct {
+	Categories []string  `json:"categories"`
+	Action     string    `json:"action"`
+	Start      float64   `json:"start"`
+	End        float64   `json:"end"`
+	At         time.Time `json:"at"`
+}
+
+// Snapshot is the state of the daemon returned by the status command
+type Snapshot struct {
+	StartedAt time.Time      `json:"started_at"`
+	Devices   []DeviceStatus `json:"devices"`
+	// Log holds the log entries after the sequence number of the request
+	Log []LogEntry `json:"log"`
+}
+
+// Request is a command sent to the status server
+type Request struct {
+	Command  string `json:"command"`
+	ScreenID string `json:"screen_id,omitempty"`
+	// Paused is the new skipping state of the pause command
+	Paused bool `json:"paused,omitempty"`
+	// LogSince is the sequence number of the last log entry already seen
+	LogSince uint64 `json:"log_since,omitempty"`
+}
+
+// Response is the answer of the status server to a request
+type Response struct {
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Provider is implemented by the daemon to answer status requests
+type Provider interface {
+	// Devices returns the state of every device
+	Devices() []DeviceStatus
+	// SetSkippingPaused pauses or resumes skipping on a device
+	SetSkippingPaused(screenID string, paused bool) error
+	// Skip skips the next segment of a device right away
+	Skip(screenID string) error
+}
@@ -1,150 +1,443 @@
 package styles
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the color scheme of the TUI
+type Theme struct {
+	Name      string
+	Primary   lipgloss.TerminalColor
+	Secondary lipgloss.TerminalColor
+	Accent    lipgloss.TerminalColor
+	Text      lipgloss.TerminalColor
+	Muted     lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Success   lipgloss.TerminalColor
+	Warning   lipgloss.TerminalColor
+	// Monochrome themes mark active and selected elements with reverse video
+	// and underlines instead of colors
+	Monochrome bool
+}
 
 var (
-	// Color scheme
-	primaryColor   = lipgloss.Color("#3B3B3B")
-	secondaryColor = lipgloss.Color("#1F1F1F")
-	accentColor    = lipgloss.Color("#0D99FF")
-	textColor      = lipgloss.Color("#FFFFFF")
-	errorColor     = lipgloss.Color("#FF0000")
+	// DarkTheme is the default theme
+	DarkTheme = Theme{
+		Name:      "dark",
+		Primary:   lipgloss.Color("#3B3B3B"),
+		Secondary: lipgloss.Color("#1F1F1F"),
+		Accent:    lipgloss.Color("#0D99FF"),
+		Text:      lipgloss.Color("#FFFFFF"),
+		Muted:     lipgloss.Color("#999999"),
+		Error:     lipgloss.Color("#FF0000"),
+		Success:   lipgloss.Color("#00C853"),
+		Warning:   lipgloss.Color("#FFAB00"),
+	}
+
+	// LightTheme is meant for terminals with a light background
+	LightTheme = Theme{
+		Name:      "light",
+		Primary:   lipgloss.Color("#D0D0D0"),
+		Secondary: lipgloss.Color("#F5F5F5"),
+		Accent:    lipgloss.Color("#0062B1"),
+		Text:      lipgloss.Color("#1A1A1A"),
+		Muted:     lipgloss.Color("#5F5F5F"),
+		Error:     lipgloss.Color("#C62828"),
+		Success:   lipgloss.Color("#1B7F3B"),
+		Warning:   lipgloss.Color("#A65E00"),
+	}
+
+	// HighContrastTheme uses the basic ANSI colors at full intensity
+	HighContrastTheme = Theme{
+		Name:      "high-contrast",
+		Primary:   lipgloss.Color("0"),
+		Secondary: lipgloss.Color("0"),
+		Accent:    lipgloss.Color("11"),
+		Text:      lipgloss.Color("15"),
+		Muted:     lipgloss.Color("15"),
+		Error:     lipgloss.Color("9"),
+		Success:   lipgloss.Color("10"),
+		Warning:   lipgloss.Color("11"),
+	}
+
+	// MonochromeTheme uses no colors at all. It is used when NO_COLOR is set.
+	MonochromeTheme = Theme{
+		Name:       "monochrome",
+		Primary:    lipgloss.NoColor{},
+		Secondary:  lipgloss.NoColor{},
+		Accent:     lipgloss.NoColor{},
+		Text:       lipgloss.NoColor{},
+		Muted:      lipgloss.NoColor{},
+		Error:      lipgloss.NoColor{},
+		Success:    lipgloss.NoColor{},
+		Warning:    lipgloss.NoColor{},
+		Monochrome: true,
+	}
+
+	// Themes lists the themes that can be selected by name
+	Themes = []Theme{DarkTheme, LightTheme, HighContrastTheme, MonochromeTheme}
+)
+
+// ThemeByName returns the theme with the given name
+func ThemeByName(name string) (Theme, bool) {
+	for _, theme := range Themes {
+		if theme.Name == name {
+			return theme, true
+		}
+	}
+	return Theme{}, false
+}
+
+// ThemeNames returns the names of the themes
+func ThemeNames() []string {
+	names := make([]string, len(Themes))
+	for i, theme := range Themes {
+		names[i] = theme.Name
+	}
+	return names
+}
+
+// NoColor reports whether colors were turned off with NO_COLOR
+// (https://no-color.org/)
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// UnicodeSupported guesses from the locale whether the terminal can show
+// Unicode box drawing characters
+func UnicodeSupported() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") != ""
+}
+
+// Configure applies the theme with the given name, or the default theme when
+// name is empty: dark, or monochrome when NO_COLOR is set. Boxes are drawn
+// in ASCII when ascii is set or the terminal does not seem to support
+// Unicode.
+func Configure(name string, ascii bool) error {
+	theme := DarkTheme
+	if NoColor() {
+		theme = MonochromeTheme
+	}
+	if name != "" {
+		var ok bool
+		if theme, ok = ThemeByName(name); !ok {
+			return fmt.Errorf("unknown theme %q, valid themes are %s", name, strings.Join(ThemeNames(), ", "))
+		}
+	}
+	Use(theme, ascii || !UnicodeSupported())
+	return nil
+}
+
+// asciiBorder draws boxes with plain ASCII characters
+var asciiBorder = lipgloss.Border{
+	Top:          "-",
+	Bottom:       "-",
+	Left:         "|",
+	Right:        "|",
+	TopLeft:      "+",
+	TopRight:     "+",
+	BottomLeft:   "+",
+	BottomRight:  "+",
+	MiddleLeft:   "+",
+	MiddleRight:  "+",
+	Middle:       "+",
+	MiddleTop:    "+",
+	MiddleBottom: "+",
+}
+
+// defaultWidth is the width of the layout before the terminal size is known
+const defaultWidth = 100
+
+var (
+	// current holds the settings the styles were built with
+	current = struct {
+		theme Theme
+		ascii bool
+		width int
+	}{theme: DarkTheme, width: defaultWidth}
 
 	// Base styles
+	Container lipgloss.Style
+	Title     lipgloss.Style
+	Subtitle  lipgloss.Style
+
+	// Tab styles
+	TabActive   lipgloss.Style
+	TabInactive lipgloss.Style
+
+	// Selection list styles
+	SelectionList       lipgloss.Style
+	SelectionItem       lipgloss.Style
+	SelectionItemActive lipgloss.Style
+
+	// Checkbox styles
+	Checkbox        lipgloss.Style
+	CheckboxChecked lipgloss.Style
+
+	// Input styles
+	Input lipgloss.Style
+
+	// Button styles
+	Button       lipgloss.Style
+	ButtonDanger lipgloss.Style
+	ButtonSmall  lipgloss.Style
+	Button100    lipgloss.Style
+
+	// Footer styles
+	Footer lipgloss.Style
+
+	// Status styles
+	StatusSuccess lipgloss.Style
+	StatusError   lipgloss.Style
+	StatusWarning lipgloss.Style
+
+	// Diff styles
+	DiffAdded   lipgloss.Style
+	DiffRemoved lipgloss.Style
+
+	// Dialog styles
+	Dialog lipgloss.Style
+
+	// Device editor styles
+	EditDeviceContainer lipgloss.Style
+
+	// List styles
+	DevicesManager lipgloss.Style
+	Element        lipgloss.Style
+	ElementName    lipgloss.Style
+
+	// API Key styles
+	APIKeyGrid lipgloss.Style
+
+	// Skip Categories styles
+	SkipCategoriesManager lipgloss.Style
+
+	// Channel Whitelist styles
+	ChannelWhitelistManager lipgloss.Style
+
+	// Ad Skip/Mute styles
+	AdSkipMuteContainer lipgloss.Style
+
+	// Autoplay styles
+	AutoplayContainer lipgloss.Style
+)
+
+func init() {
+	build()
+}
+
+// Use rebuilds the styles with a theme. With ascii set, boxes are drawn with
+// plain ASCII characters.
+func Use(theme Theme, ascii bool) {
+	current.theme = theme
+	current.ascii = ascii
+	build()
+}
+
+// Resize rebuilds the styles for a terminal of the given width
+func Resize(width int) {
+	if width <= 0 {
+		width = defaultWidth
+	}
+	current.width = width
+	build()
+}
+
+// ASCII reports whether boxes are drawn with plain ASCII characters
+func ASCII() bool {
+	return current.ascii
+}
+
+// ContentWidth is the width available inside Container
+func ContentWidth() int {
+	return max(current.width-Container.GetHorizontalFrameSize(), 1)
+}
+
+// build assigns every style from the current theme, border set and width
+func build() {
+	t := current.theme
+	width := current.width
+
+	normalBorder, roundedBorder := lipgloss.NormalBorder(), lipgloss.RoundedBorder()
+	if current.ascii {
+		normalBorder, roundedBorder = asciiBorder, asciiBorder
+	}
+
 	Container = lipgloss.NewStyle().
-			Padding(1).
-			MarginTop(1).
-			Width(100).
-			Background(secondaryColor).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(primaryColor)
+		Padding(1).
+		MarginTop(1).
+		Width(width - 2).
+		Background(t.Secondary).
+		Border(normalBorder).
+		BorderForeground(t.Primary)
 
 	Title = lipgloss.NewStyle().
 		Bold(true).
-		Width(100).
+		Width(ContentWidth()).
 		Padding(0, 1).
-		Background(primaryColor).
-		Foreground(textColor)
+		Background(t.Primary).
+		Foreground(t.Text).
+		Underline(t.Monochrome)
 
 	Subtitle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#999999")).
-			MarginLeft(1)
+		Foreground(t.Muted).
+		MarginLeft(1)
 
 	// Tab styles
 	TabActive = lipgloss.NewStyle().
-			Background(accentColor).
-			Foreground(textColor).
-			Padding(0, 2)
+		Background(t.Accent).
+		Foreground(t.Text).
+		Bold(t.Monochrome).
+		Reverse(t.Monochrome).
+		Padding(0, 2)
 
 	TabInactive = lipgloss.NewStyle().
-			Background(primaryColor).
-			Foreground(textColor).
-			Padding(0, 2)
+		Background(t.Primary).
+		Foreground(t.Text).
+		Padding(0, 2)
 
 	// Selection list styles
 	SelectionList = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
-			Padding(0, 1).
-			MarginTop(1)
+		Border(roundedBorder).
+		BorderForeground(t.Primary).
+		Padding(0, 1).
+		MarginTop(1)
 
 	SelectionItem = lipgloss.NewStyle().
-			PaddingLeft(1)
+		PaddingLeft(1)
 
 	SelectionItemActive = SelectionItem.Copy().
-				Foreground(accentColor).
-				Bold(true)
+		Foreground(t.Accent).
+		Bold(true).
+		Underline(t.Monochrome)
 
 	// Checkbox styles
 	Checkbox = lipgloss.NewStyle().
-			PaddingLeft(1).
-			MarginTop(1)
+		PaddingLeft(1).
+		MarginTop(1)
 
 	CheckboxChecked = lipgloss.NewStyle().
-			Foreground(accentColor).
-			Bold(true)
+		Foreground(t.Accent).
+		Bold(true).
+		Underline(t.Monochrome)
 
 	// Input styles
 	Input = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(primaryColor).
+		Border(roundedBorder).
+		BorderForeground(t.Primary).
 		Padding(0, 1).
 		MarginTop(1)
 
 	// Button styles
 	Button = lipgloss.NewStyle().
-		Background(accentColor).
-		Foreground(textColor).
+		Background(t.Accent).
+		Foreground(t.Text).
+		Reverse(t.Monochrome).
 		Padding(0, 2).
 		Align(lipgloss.Center).
 		MarginTop(1)
 
 	ButtonDanger = Button.Copy().
-			Background(errorColor)
+		Background(t.Error)
+
+	ButtonSmall = lipgloss.NewStyle().
+		Height(3).
+		Padding(0)
+
+	Button100 = lipgloss.NewStyle().
+		Width(width)
 
 	// Footer styles
 	Footer = lipgloss.NewStyle().
-		Background(primaryColor).
-		Foreground(textColor).
+		Background(t.Primary).
+		Foreground(t.Text).
+		Width(width).
 		Padding(0, 1).
 		Align(lipgloss.Right)
 
-	// Button styles
-	ButtonSmall = lipgloss.NewStyle().
-			Height(3).
-			Padding(0)
+	// Status styles
+	StatusSuccess = lipgloss.NewStyle().
+		Width(width).
+		Foreground(t.Success).
+		Padding(0, 1)
 
-	Button100 = lipgloss.NewStyle().
-			Width(100)
+	StatusError = lipgloss.NewStyle().
+		Width(width).
+		Foreground(t.Error).
+		Bold(t.Monochrome).
+		Padding(0, 1)
+
+	StatusWarning = lipgloss.NewStyle().
+		Width(width).
+		Foreground(t.Warning).
+		Bold(true).
+		Padding(0, 1)
+
+	// Diff styles
+	DiffAdded = lipgloss.NewStyle().
+		Foreground(t.Success)
+
+	DiffRemoved = lipgloss.NewStyle().
+		Foreground(t.Error)
 
 	// Dialog styles
 	Dialog = lipgloss.NewStyle().
 		Padding(1, 2).
-		Width(35).
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("8"))
+		Width(min(35, width)).
+		Border(roundedBorder).
+		BorderForeground(t.Muted)
 
 	// Device editor styles
 	EditDeviceContainer = lipgloss.NewStyle().
-				Padding(1, 2, 0, 2).
-				Width(50).
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("8"))
+		Padding(1, 2, 0, 2).
+		Width(min(50, width)).
+		Border(roundedBorder).
+		BorderForeground(t.Muted)
 
 	// List styles
 	DevicesManager = lipgloss.NewStyle().
-			Width(100).
-			MaxHeight(70)
+		Width(width).
+		MaxHeight(70)
 
 	Element = lipgloss.NewStyle().
-		Width(100).
+		Width(width).
 		Padding(0, 1, 0, 1)
 
 	ElementName = lipgloss.NewStyle().
-			Width(100).
-			Align(lipgloss.Left, lipgloss.Center)
+		Width(width).
+		Align(lipgloss.Left, lipgloss.Center)
 
 	// API Key styles
 	APIKeyGrid = lipgloss.NewStyle().
-			Padding(1, 1).
-			Width(100).
-			Height(5)
+		Padding(1, 1).
+		Width(width).
+		Height(5)
 
 	// Skip Categories styles
 	SkipCategoriesManager = lipgloss.NewStyle().
-				Width(100).
-				MaxHeight(70)
+		Width(width).
+		MaxHeight(70)
 
 	// Channel Whitelist styles
 	ChannelWhitelistManager = lipgloss.NewStyle().
-				Width(100).
-				MaxHeight(70)
+		Width(width).
+		MaxHeight(70)
 
 	// Ad Skip/Mute styles
 	AdSkipMuteContainer = lipgloss.NewStyle().
-				Padding(1)
+		Padding(1)
 
 	// Autoplay styles
 	AutoplayContainer = lipgloss.NewStyle().
-				Padding(1)
-)
+		Padding(1)
+}
@@ -10,22 +10,23 @@ type Config struct {
 	MuteAds           bool
 	SkipAds           bool
 	AutoPlay          bool
-	YouTube           YouTubeConfig
-	SponsorBlock      SponsorBlockConfig
 }
 
-// YouTubeConfig holds YouTube-specific configuration
+// YouTubeConfig holds the deprecated youtube section of the config, which
+// config.Normalize merges into the top-level keys
 type YouTubeConfig struct {
-	APIKey string
+	APIKey string `json:"apikey"`
 }
 
-// SponsorBlockConfig holds SponsorBlock-specific configuration
+// SponsorBlockConfig holds the deprecated sponsorblock section of the
+// config, which config.Normalize merges into the top-level keys
 type SponsorBlockConfig struct {
-	Categories        []string
-	SkipCountTracking bool
+	Categories        []string `json:"categories"`
+	SkipCountTracking bool     `json:"skip_count_tracking"`
 }
 
 // ChannelInfo represents a channel in the whitelist
 type ChannelInfo struct {
-	ID string `json:"id"`
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
 }
@@ -0,0 +1,164 @@
+package ytlounge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// CalibrationVideo is the reference video played during calibration
+	// unless the config names another. It has been public and embeddable
+	// for well over a decade, plays in every region, and is long enough for
+	// the seeks.
+	CalibrationVideo = "dQw4w9WgXcQ"
+
+	// calibrationFirstSeek and calibrationSeekStep are the positions in
+	// seconds of the calibration seeks
+	calibrationFirstSeek = 30
+	calibrationSeekStep  = 25
+
+	// calibrationTolerance is how far in seconds a reported position may be
+	// from a seek target to count as the result of the seek
+	calibrationTolerance = 5
+
+	// calibrationTimeout bounds the wait for each state change
+	calibrationTimeout = 20 * time.Second
+)
+
+// errCalibrationTimeout is returned when the device stops reporting its state
+var errCalibrationTimeout = errors.New("the device did not report its playback state in time")
+
+// CalibrationStep reports the lag measured by one calibration seek
+type CalibrationStep struct {
+	Seek  int
+	Seeks int
+	// Lag is how long in seconds the device took to play from the seek target
+	Lag float64
+}
+
+// playingState is a playing position reported by onStateChange
+type playingState struct {
+	position float64
+	at       time.Time
+}
+
+// Calibrate measures the offset of the device. It plays videoID, or
+// CalibrationVideo if empty, seeks seeks times and measures how far the
+// position reported by onStateChange trails each seek. The suggested offset
+// is the median lag in seconds. progress, if not nil, is called after every
+// seek.
+func (y *YtLoungeApi) Calibrate(ctx context.Context, videoID string, seeks int, progress func(CalibrationStep)) (float64, error) {
+	if videoID == "" {
+		videoID = CalibrationVideo
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := y.Connect(ctx); err != nil {
+		return 0, fmt.Errorf("failed to connect to the device: %w", err)
+	}
+
+	states := make(chan playingState, 16)
+	go y.SubscribeMonitored(ctx, func(eventType string, args []interface{}) {
+		if state, ok := parsePlayingState(eventType, args); ok {
+			select {
+			case states <- state:
+			default:
+			}
+		}
+	})
+
+	if err := y.PlayVideo(videoID); err != nil {
+		return 0, fmt.Errorf("failed to play the calibration video: %w", err)
+	}
+	if _, err := waitForPlaying(ctx, states); err != nil {
+		return 0, fmt.Errorf("the calibration video did not start: %w", err)
+	}
+
+	lags := make([]float64, 0, seeks)
+	for i := 0; i < seeks; i++ {
+		target := float64(calibrationFirstSeek + i*calibrationSeekStep)
+
+		// Drop states reported before the seek
+		for len(states) > 0 {
+			<-states
+		}
+
+		start := time.Now()
+		if err := y.SeekTo(target); err != nil {
+			return 0, fmt.Errorf("failed to seek: %w", err)
+		}
+
+		for {
+			state, err := waitForPlaying(ctx, states)
+			if err != nil {
+				return 0, err
+			}
+			if math.Abs(state.position-target) > calibrationTolerance {
+				continue
+			}
+
+			// The time since the seek, less what the device has played
+			// since it got there
+			lag := math.Max(0, state.at.Sub(start).Seconds()-(state.position-target))
+			lags = append(lags, lag)
+			if progress != nil {
+				progress(CalibrationStep{Seek: i + 1, Seeks: seeks, Lag: lag})
+			}
+			break
+		}
+	}
+
+	return median(lags), nil
+}
+
+// parsePlayingState returns the position of an onStateChange event that
+// reports playback
+func parsePlayingState(eventType string, args []interface{}) (playingState, bool) {
+	if eventType != "onStateChange" || len(args) == 0 {
+		return playingState{}, false
+	}
+	data, ok := args[0].(map[string]interface{})
+	if !ok || data["state"] != "1" {
+		return playingState{}, false
+	}
+	position, ok := EventFloat(data["currentTime"])
+	if !ok {
+		return playingState{}, false
+	}
+	return playingState{position: position, at: time.Now()}, true
+}
+
+// waitForPlaying returns the next playing state
+func waitForPlaying(ctx context.Context, states <-chan playingState) (playingState, error) {
+	timer := time.NewTimer(calibrationTimeout)
+	defer timer.Stop()
+
+	select {
+	case state := <-states:
+		return state, nil
+	case <-timer.C:
+		return playingState{}, errCalibrationTimeout
+	case <-ctx.Done():
+		return playingState{}, ctx.Err()
+	}
+}
+
+// median returns the median of values, or 0 for none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -1,81 +1,455 @@
 package ytlounge
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
 )
 
+// BaseURL is the root of the YouTube Lounge API
+const BaseURL = "https://www.youtube.com/api/lounge"
+
+// requestTimeout bounds every lounge request but the event long poll
+const requestTimeout = 10 * time.Second
+
+var (
+	// errNotConnected is returned for commands sent without a session
+	errNotConnected = errors.New("not connected to the lounge")
+	// errSessionExpired is returned when the lounge closed the session
+	errSessionExpired = errors.New("the lounge session expired")
+	// errTokenExpired is returned when the lounge rejected the lounge token
+	errTokenExpired = errors.New("the lounge token expired")
+)
+
+// Event is an event received from the lounge
+type Event struct {
+	// ID is the position of the event in the session
+	ID   int
+	Type string
+	Args []interface{}
+}
+
 // Client represents a YouTube Lounge client
 type Client struct {
 	cfg      *config.Config
 	http     *http.Client
 	baseURL  string
 	ScreenID string
+	// deviceID identifies the client in the lounge
+	deviceID string
+	// tokens keeps the lounge token across restarts, if not nil
+	tokens *TokenStore
+
+	// mu guards the session, which the subscription and commands share
+	mu          sync.Mutex
+	loungeToken string
+	sid         string
+	gsessionID  string
+	aid         int
+	rid         int
+	ofs         int
+}
+
+// NewClient creates a YouTube Lounge client for the device of an effective
+// config, as returned by config.Config.ForDevice. The lounge token is taken
+// from tokens, or else fetched.
+func NewClient(cfg *config.Config, tokens *TokenStore) (*Client, error) {
+	if len(cfg.Devices) != 1 {
+		return nil, fmt.Errorf("expected the config of one device, got %d devices", len(cfg.Devices))
+	}
+	client := NewScreenClient(cfg, cfg.Devices[0].ScreenID, tokens)
+	if client.loadToken() {
+		return client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if err := client.RefreshToken(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
-// NewClient creates a new YouTube Lounge client
-func NewClient(cfg *config.Config) (*Client, error) {
-	client := &Client{
+// NewScreenClient creates a YouTube Lounge client for a known screen ID. The
+// lounge token is taken from tokens or fetched on Connect. Fetched tokens
+// are stored in tokens, which may be nil.
+func NewScreenClient(cfg *config.Config, screenID string, tokens *TokenStore) *Client {
+	return &Client{
 		cfg: cfg,
-		http: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		baseURL: "https://www.youtube.com/api/lounge",
+		// The event long poll outlives any client timeout; requests are
+		// bounded by their context instead
+		http:     &http.Client{},
+		baseURL:  BaseURL,
+		ScreenID: screenID,
+		deviceID: newDeviceID(),
+		tokens:   tokens,
 	}
+}
 
-	// Get screen ID
-	screenID, err := client.GetScreenID(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get screen ID: %w", err)
+// newDeviceID returns a random ID for a client
+func newDeviceID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// loadToken takes the lounge token from the token store and reports
+// whether there was one
+func (c *Client) loadToken() bool {
+	if c.tokens == nil {
+		return false
+	}
+	// An unreadable token file only means fetching a new token
+	token, ok, err := c.tokens.Get(c.ScreenID)
+	if err != nil || !ok || token == "" {
+		return false
 	}
-	client.ScreenID = screenID
 
-	return client, nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loungeToken = token
+	return true
 }
 
-// GetScreenID retrieves the screen ID for the YouTube Lounge
-func (c *Client) GetScreenID(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/bc/bind", c.baseURL), nil)
+// RefreshToken fetches a new lounge token for the screen. The current
+// session, if any, is dropped.
+func (c *Client) RefreshToken(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	form := url.Values{"screen_ids": {c.ScreenID}}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/pairing/get_lounge_token_batch", strings.NewReader(form.Encode()))
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to create lounge token request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to get lounge token: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get lounge token: unexpected status %s", resp.Status)
+	}
 
 	var result struct {
-		ScreenID string `json:"screenId"`
+		Screens []Screen `json:"screens"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return fmt.Errorf("failed to decode lounge token response: %w", err)
+	}
+	for _, screen := range result.Screens {
+		if screen.ScreenID == c.ScreenID && screen.LoungeToken != "" {
+			c.mu.Lock()
+			c.loungeToken = screen.LoungeToken
+			c.sid, c.gsessionID = "", ""
+			c.mu.Unlock()
+
+			if c.tokens != nil {
+				// The stored token only saves a request next time
+				c.tokens.Set(c.ScreenID, screen.LoungeToken)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no lounge token returned for screen %s", c.ScreenID)
+}
+
+// Connected reports whether the client has a lounge session
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sid != ""
+}
+
+// Connect opens a lounge session, joining the lounge under the join name of
+// the config. The events sent with the session are passed to onEvent. The
+// lounge token is fetched first if missing, and again if it expired.
+func (c *Client) Connect(ctx context.Context, onEvent func(Event)) error {
+	c.mu.Lock()
+	haveToken := c.loungeToken != ""
+	c.mu.Unlock()
+	if !haveToken && !c.loadToken() {
+		if err := c.RefreshToken(ctx); err != nil {
+			return err
+		}
 	}
 
-	return result.ScreenID, nil
+	err := c.connect(ctx, onEvent)
+	if errors.Is(err, errTokenExpired) {
+		if err := c.RefreshToken(ctx); err != nil {
+			return err
+		}
+		err = c.connect(ctx, onEvent)
+	}
+	return err
 }
 
-// SendCommand sends a command to the YouTube Lounge
-func (c *Client) SendCommand(ctx context.Context, screenID string, command interface{}) error {
-	data, err := json.Marshal(command)
+// connect sends the bind request opening a session
+func (c *Client) connect(ctx context.Context, onEvent func(Event)) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	token := c.loungeToken
+	c.sid, c.gsessionID = "", ""
+	c.aid, c.rid, c.ofs = 0, 1, 0
+	c.mu.Unlock()
+
+	query := url.Values{
+		"RID":                 {"1"},
+		"VER":                 {"8"},
+		"CVER":                {"1"},
+		"auth_failure_option": {"send_error"},
+	}
+	form := url.Values{
+		"app":           {"web"},
+		"mdx-version":   {"3"},
+		"name":          {c.cfg.JoinName},
+		"id":            {c.deviceID},
+		"device":        {"REMOTE_CONTROL"},
+		"capabilities":  {"que,dsdtr,atp"},
+		"method":        {"setPlaylist"},
+		"magnaKey":      {"cloudPairedDevice"},
+		"ui":            {"false"},
+		"theme":         {"cl"},
+		"deviceContext": {"user_agent=dunno&window_width_points=&window_height_points=&os_name=android&ms="},
+	}
+	resp, err := c.post(ctx, query, form, token)
 	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := c.checkStatus(resp); err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		fmt.Sprintf("%s/bc/bind?screen_id=%s", c.baseURL, screenID),
-		bytes.NewReader(data))
+	if err := readEvents(resp.Body, c.track(onEvent)); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	if !c.Connected() {
+		return errors.New("failed to connect: the lounge did not open a session")
+	}
+	return nil
+}
+
+// Subscribe long-polls the session for events, passing them to onEvent,
+// until ctx is done or the session ends
+func (c *Client) Subscribe(ctx context.Context, onEvent func(Event)) error {
+	for {
+		query, token, err := c.sessionQuery()
+		if err != nil {
+			return err
+		}
+		query.Set("RID", "rpc")
+		query.Set("CI", "0")
+		query.Set("TYPE", "xmlhttp")
+
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/bc/bind?"+query.Encode(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to create subscribe request: %w", err)
+		}
+		req.Header.Set("X-YouTube-LoungeId-Token", token)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		if err := c.checkStatus(resp); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		err = readEvents(resp.Body, c.track(onEvent))
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read lounge events: %w", err)
+		}
+	}
+}
+
+// SendCommand sends a command with its parameters to the screen
+func (c *Client) SendCommand(ctx context.Context, command string, params map[string]string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	query, token, err := c.sessionQuery()
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
+	c.rid++
+	query.Set("RID", strconv.Itoa(c.rid))
+	form := url.Values{
+		"count":    {"1"},
+		"ofs":      {strconv.Itoa(c.ofs)},
+		"req0__sc": {command},
+	}
+	c.ofs++
+	c.mu.Unlock()
+	for key, value := range params {
+		form.Set("req0_"+key, value)
+	}
 
-	_, err = c.http.Do(req)
-	return err
+	resp, err := c.post(ctx, query, form, token)
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", command, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return c.checkStatus(resp)
+}
+
+// sessionQuery returns the query parameters identifying the session and the
+// lounge token
+func (c *Client) sessionQuery() (url.Values, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sid == "" {
+		return nil, "", errNotConnected
+	}
+	return url.Values{
+		"name":          {c.cfg.JoinName},
+		"loungeIdToken": {c.loungeToken},
+		"SID":           {c.sid},
+		"gsessionid":    {c.gsessionID},
+		"AID":           {strconv.Itoa(c.aid)},
+		"device":        {"REMOTE_CONTROL"},
+		"app":           {"youtube-desktop"},
+		"VER":           {"8"},
+		"v":             {"2"},
+	}, c.loungeToken, nil
+}
+
+// post sends a form to the bind endpoint
+func (c *Client) post(ctx context.Context, query, form url.Values, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/bc/bind?"+query.Encode(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-YouTube-LoungeId-Token", token)
+	return c.http.Do(req)
+}
+
+// checkStatus maps the status of a bind response to an error, dropping the
+// session or the lounge token the lounge rejected
+func (c *Client) checkStatus(resp *http.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		c.loungeToken, c.sid, c.gsessionID = "", "", ""
+		return errTokenExpired
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusGone:
+		c.sid, c.gsessionID = "", ""
+		return errSessionExpired
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// track returns an event handler recording the session state of events and
+// passing the others to onEvent
+func (c *Client) track(onEvent func(Event)) func(Event) {
+	return func(event Event) {
+		c.mu.Lock()
+		c.aid = event.ID
+		sessionEvent := true
+		switch event.Type {
+		case "c":
+			if len(event.Args) > 0 {
+				c.sid, _ = event.Args[0].(string)
+			}
+		case "S":
+			if len(event.Args) > 0 {
+				c.gsessionID, _ = event.Args[0].(string)
+			}
+		default:
+			sessionEvent = false
+		}
+		c.mu.Unlock()
+
+		if !sessionEvent && onEvent != nil {
+			onEvent(event)
+		}
+	}
+}
+
+// readEvents decodes the chunks of a bind response. Every chunk is its
+// length on a line of its own followed by a JSON array of events, each
+// encoded as [id, [type, args...]].
+func readEvents(r io.Reader, onEvent func(Event)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var chunk json.RawMessage
+		if err := dec.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// Skip the chunk lengths, the decoder finds the end of every chunk
+		if chunk[0] != '[' {
+			continue
+		}
+
+		var events [][2]json.RawMessage
+		if err := json.Unmarshal(chunk, &events); err != nil {
+			return err
+		}
+		for _, raw := range events {
+			var event Event
+			var payload []interface{}
+			if err := json.Unmarshal(raw[0], &event.ID); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw[1], &payload); err != nil {
+				return err
+			}
+			if len(payload) == 0 {
+				continue
+			}
+			event.Type, _ = payload[0].(string)
+			event.Args = payload[1:]
+			onEvent(event)
+		}
+	}
+}
+
+// eventData returns the first argument of a lounge event as an object. Events
+// may arrive without arguments, so handlers read their data through it.
+func eventData(args []interface{}) (map[string]interface{}, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	data, ok := args[0].(map[string]interface{})
+	return data, ok
+}
+
+// EventFloat reads a number that lounge events send as a string or a number
+func EventFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return 0, false
 }
@@ -0,0 +1,188 @@
+package ytlounge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/config"
+)
+
+// chunk encodes events as a chunk of a bind response
+func chunk(events string) string {
+	return fmt.Sprintf("%d\n%s\n", len(events)+1, events)
+}
+
+// fakeLounge serves the lounge endpoints for one screen. The first event
+// poll returns events, later polls report the session as expired.
+type fakeLounge struct {
+	mu       sync.Mutex
+	polls    int
+	commands []url.Values
+	queries  []url.Values
+}
+
+func (f *fakeLounge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/pairing/get_lounge_token_batch":
+		r.ParseForm()
+		fmt.Fprintf(w, `{"screens":[{"screenId":%q,"loungeToken":"token","expiration":0}]}`, r.PostForm.Get("screen_ids"))
+		return
+	case r.URL.Path != "/bc/bind":
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("X-YouTube-LoungeId-Token") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == "POST" && r.URL.Query().Get("RID") == "1":
+		fmt.Fprint(w, chunk(`[[0,["c","sid","",8]],[1,["S","gsession"]],[2,["loungeStatus",{"devices":"[]"}]]]`))
+	case r.Method == "POST":
+		r.ParseForm()
+		f.commands = append(f.commands, r.PostForm)
+		f.queries = append(f.queries, r.URL.Query())
+	case f.polls == 0:
+		f.polls++
+		fmt.Fprint(w, chunk(`[[3,["onStateChange",{"currentTime":"12.5","state":"1","videoId":"vid"}]]]`))
+		fmt.Fprint(w, chunk(`[[4,["nowPlaying",{"title":"Café"}]],[5,["noop"]]]`))
+	default:
+		f.polls++
+		f.queries = append(f.queries, r.URL.Query())
+		w.WriteHeader(http.StatusGone)
+	}
+}
+
+// newTestClient returns a client of the screen "screen" served by lounge
+func newTestClient(t *testing.T, lounge http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(lounge)
+	t.Cleanup(server.Close)
+
+	client := NewScreenClient(&config.Config{JoinName: "test"}, "screen", nil)
+	client.baseURL = server.URL
+	return client
+}
+
+func TestClientSession(t *testing.T) {
+	lounge := &fakeLounge{}
+	client := newTestClient(t, lounge)
+	ctx := context.Background()
+
+	var connectEvents []string
+	if err := client.Connect(ctx, func(event Event) { connectEvents = append(connectEvents, event.Type) }); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if !client.Connected() {
+		t.Fatal("not connected after Connect")
+	}
+	if want := []string{"loungeStatus"}; !reflect.DeepEqual(connectEvents, want) {
+		t.Errorf("connect events = %q, want %q", connectEvents, want)
+	}
+
+	var events []Event
+	err := client.Subscribe(ctx, func(event Event) { events = append(events, event) })
+	if !errors.Is(err, errSessionExpired) {
+		t.Errorf("Subscribe error = %v, want %v", err, errSessionExpired)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	if events[0].Type != "onStateChange" || events[0].ID != 3 {
+		t.Errorf("first event = %+v", events[0])
+	}
+	data, _ := events[0].Args[0].(map[string]interface{})
+	if position, ok := EventFloat(data["currentTime"]); !ok || position != 12.5 {
+		t.Errorf("currentTime = %v", data["currentTime"])
+	}
+	if data, _ := events[1].Args[0].(map[string]interface{}); data["title"] != "Café" {
+		t.Errorf("title = %v, want Café", data["title"])
+	}
+	if events[2].Type != "noop" || len(events[2].Args) != 0 {
+		t.Errorf("last event = %+v", events[2])
+	}
+
+	// The second poll resumed after the last event and expired the session
+	if got := lounge.queries[0].Get("AID"); got != "5" {
+		t.Errorf("AID of the second poll = %q, want 5", got)
+	}
+	if client.Connected() {
+		t.Error("still connected after the session expired")
+	}
+	if err := client.SendCommand(ctx, "seekTo", nil); !errors.Is(err, errNotConnected) {
+		t.Errorf("SendCommand without a session = %v, want %v", err, errNotConnected)
+	}
+}
+
+func TestClientSendCommand(t *testing.T) {
+	lounge := &fakeLounge{}
+	client := newTestClient(t, lounge)
+	ctx := context.Background()
+
+	if err := client.Connect(ctx, nil); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.SendCommand(ctx, "seekTo", map[string]string{"newTime": "42.000"}); err != nil {
+			t.Fatalf("SendCommand: %v", err)
+		}
+	}
+
+	want := url.Values{
+		"count":        {"1"},
+		"ofs":          {"1"},
+		"req0__sc":     {"seekTo"},
+		"req0_newTime": {"42.000"},
+	}
+	if got := lounge.commands[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("second command = %v, want %v", got, want)
+	}
+	query := lounge.queries[1]
+	if query.Get("SID") != "sid" || query.Get("gsessionid") != "gsession" || query.Get("RID") != "3" {
+		t.Errorf("second command query = %v", query)
+	}
+}
+
+func TestClientReplacesExpiredStoredToken(t *testing.T) {
+	tokens := NewTokenStore(filepath.Join(t.TempDir(), TokensFileName))
+	if err := tokens.Set("screen", "expired"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, &fakeLounge{})
+	client.tokens = tokens
+	if err := client.Connect(context.Background(), nil); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	token, ok, err := tokens.Get("screen")
+	if err != nil || !ok || token != "token" {
+		t.Errorf("stored token = %q, %v, %v, want the refreshed token", token, ok, err)
+	}
+}
+
+func TestReadEventsRejectsMalformedChunks(t *testing.T) {
+	for _, body := range []string{
+		chunk(`[[0,["c"]]`),
+		chunk(`[["id",["c"]]]`),
+		chunk(`[[0,"c"]]`),
+	} {
+		err := readEvents(strings.NewReader(body), func(Event) {})
+		if err == nil {
+			t.Errorf("readEvents(%q) succeeded", body)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ytlounge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PairingURL is the lounge endpoint that resolves a TV code to its screen
+const PairingURL = "https://www.youtube.com/api/lounge/pairing/get_screen"
+
+// pairingCodeLength is the number of digits of a TV code
+const pairingCodeLength = 12
+
+var (
+	// ErrPairingCodeFormat is returned for TV codes that are not 12 digits
+	ErrPairingCodeFormat = fmt.Errorf("a TV code is %d digits", pairingCodeLength)
+	// ErrPairingCodeRejected is returned for TV codes that are wrong or have
+	// expired
+	ErrPairingCodeRejected = errors.New("the TV code is wrong or has expired")
+)
+
+// Screen is a TV resolved from a TV code
+type Screen struct {
+	ScreenID    string `json:"screenId"`
+	Name        string `json:"name"`
+	LoungeToken string `json:"loungeToken"`
+	// Expiration is when the lounge token expires, in milliseconds since
+	// the epoch
+	Expiration int64 `json:"expiration"`
+}
+
+// ParsePairingCode returns the digits of a TV code as shown under "Link with
+// TV code", ignoring spaces and dashes
+func ParsePairingCode(code string) (string, error) {
+	var digits strings.Builder
+	for _, r := range code {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-':
+		default:
+			return "", ErrPairingCodeFormat
+		}
+	}
+	if digits.Len() != pairingCodeLength {
+		return "", ErrPairingCodeFormat
+	}
+	return digits.String(), nil
+}
+
+// Pair resolves a TV code to its screen. pairingURL is normally PairingURL.
+func Pair(ctx context.Context, httpClient *http.Client, pairingURL, code string) (*Screen, error) {
+	code, err := ParsePairingCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{"pairing_code": {code}}
+	req, err := http.NewRequestWithContext(ctx, "POST", pairingURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pairing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pair: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest:
+		return nil, ErrPairingCodeRejected
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to pair: unexpected status %s", resp.Status)
+	}
+
+	var result struct {
+		Screen Screen `json:"screen"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode pairing response: %w", err)
+	}
+	if result.Screen.ScreenID == "" {
+		return nil, ErrPairingCodeRejected
+	}
+
+	return &result.Screen, nil
+}
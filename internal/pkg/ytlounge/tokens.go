@@ -0,0 +1,89 @@
+package ytlounge
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/fsutil"
+)
+
+// TokensFileName is the name of the lounge token file in the data directory
+const TokensFileName = "lounge_tokens.json"
+
+// TokenStore persists lounge tokens by screen ID in a JSON file
+type TokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewTokenStore creates a token store backed by the file at path
+func NewTokenStore(path string) *TokenStore {
+	return &TokenStore{path: path}
+}
+
+// Get returns the lounge token stored for a screen ID
+func (t *TokenStore) Get(screenID string) (string, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tokens, err := t.load()
+	if err != nil {
+		return "", false, err
+	}
+	token, ok := tokens[screenID]
+	return token, ok, nil
+}
+
+// Set stores the lounge token for a screen ID
+func (t *TokenStore) Set(screenID, token string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tokens, err := t.load()
+	if err != nil {
+		return err
+	}
+	tokens[screenID] = token
+	return t.save(tokens)
+}
+
+// Delete removes the lounge token of a screen ID
+func (t *TokenStore) Delete(screenID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tokens, err := t.load()
+	if err != nil {
+		return err
+	}
+	delete(tokens, screenID)
+	return t.save(tokens)
+}
+
+func (t *TokenStore) load() (map[string]string, error) {
+	tokens := make(map[string]string)
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (t *TokenStore) save(tokens map[string]string) error {
+	data, err := json.MarshalIndent(tokens, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return err
+	}
+	return fsutil.ReplaceFile(t.path, data, 0o600)
+}
@@ -3,8 +3,10 @@ package ytlounge
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/authrequest/go-SponsorBlockTV/internal/pkg/api"
@@ -38,20 +40,21 @@ type VolumeState struct {
 
 // YtLoungeApi represents a YouTube Lounge API client
 type YtLoungeApi struct {
-	client             *Client
-	config             *config.Config
-	apiHelper          *api.APIHelper
-	logger             *logrus.Logger
-	volumeState        map[string]interface{}
-	playbackSpeed      float64
+	client        *Client
+	config        *config.Config
+	apiHelper     *api.APIHelper
+	logger        *logrus.Logger
+	volumeState   map[string]interface{}
+	playbackSpeed float64
+	// subscribeTask ends the running subscription
 	subscribeTask      context.CancelFunc
-	watchdogTask       context.CancelFunc
 	callback           func(eventType string, args []interface{})
 	shortsDisconnected bool
-	autoPlay           bool
-	muteAds            bool
-	skipAds            bool
-	commandMutex       sync.Mutex
+	// The options are set by config reloads while events are handled
+	autoPlay     atomic.Bool
+	muteAds      atomic.Bool
+	skipAds      atomic.Bool
+	commandMutex sync.Mutex
 }
 
 // NewYtLoungeApi creates a new YtLoungeApi instance
@@ -67,142 +70,131 @@ func NewYtLoungeApi(client *Client, apiHelper *api.APIHelper, logger *logrus.Log
 
 // SetMuteAds sets whether to mute ads
 func (y *YtLoungeApi) SetMuteAds(mute bool) {
-	y.muteAds = mute
+	y.muteAds.Store(mute)
 }
 
 // SetSkipAds sets whether to skip ads
 func (y *YtLoungeApi) SetSkipAds(skip bool) {
-	y.skipAds = skip
+	y.skipAds.Store(skip)
 }
 
 // SetAutoPlay sets whether to enable autoplay
 func (y *YtLoungeApi) SetAutoPlay(autoPlay bool) {
-	y.autoPlay = autoPlay
+	y.autoPlay.Store(autoPlay)
 }
 
-// SubscribeMonitored starts a monitored subscription to the lounge
-func (y *YtLoungeApi) SubscribeMonitored(ctx context.Context, callback func(eventType string, args []interface{})) error {
-	y.callback = callback
+// watchdogTimeout is how long a subscription may go without events. The
+// lounge sends a noop event every 30 seconds.
+const watchdogTimeout = 35 * time.Second
 
-	// Cancel existing tasks if any
-	if y.watchdogTask != nil {
-		y.watchdogTask()
-	}
-	if y.subscribeTask != nil {
-		y.subscribeTask()
-	}
+// errWatchdog is returned when the lounge stopped sending events
+var errWatchdog = errors.New("the lounge stopped sending events")
 
-	// Create new context for subscription
-	subCtx, subCancel := context.WithCancel(ctx)
-	y.subscribeTask = subCancel
+// Connect opens a lounge session. The events sent with the session are
+// passed to ProcessEvent.
+func (y *YtLoungeApi) Connect(ctx context.Context) error {
+	return y.client.Connect(ctx, func(event Event) {
+		y.ProcessEvent(event.Type, event.Args)
+	})
+}
 
-	// Start subscription
-	go y.subscribe(subCtx)
+// SubscribeMonitored connects to the lounge if needed and passes its events
+// to ProcessEvent and then callback until ctx is done or the subscription
+// ends. A watchdog ends the subscription when the lounge goes quiet.
+func (y *YtLoungeApi) SubscribeMonitored(ctx context.Context, callback func(eventType string, args []interface{})) error {
+	y.callback = callback
 
-	// Start watchdog
-	watchCtx, watchCancel := context.WithCancel(ctx)
-	y.watchdogTask = watchCancel
-	go y.watchdog(watchCtx)
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	y.subscribeTask = cancel
 
-	return nil
-}
-
-func (y *YtLoungeApi) watchdog(ctx context.Context) {
-	ticker := time.NewTicker(35 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if y.subscribeTask != nil {
-				y.subscribeTask()
-				y.subscribeTask = nil
-			}
+	if !y.client.Connected() {
+		if err := y.Connect(subCtx); err != nil {
+			return err
 		}
 	}
-}
 
-func (y *YtLoungeApi) subscribe(ctx context.Context) {
-	// Implementation of subscription logic here
-	// This would involve setting up a websocket or long-polling connection
-	// to receive events from the YouTube Lounge API
+	watchdog := time.AfterFunc(watchdogTimeout, cancel)
+	defer watchdog.Stop()
+	err := y.client.Subscribe(subCtx, func(event Event) {
+		watchdog.Reset(watchdogTimeout)
+		y.ProcessEvent(event.Type, event.Args)
+	})
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case subCtx.Err() != nil:
+		return errWatchdog
+	}
+	return err
 }
 
 // ProcessEvent processes events from the YouTube Lounge API
 func (y *YtLoungeApi) ProcessEvent(eventType string, args []interface{}) {
 	y.logger.Debugf("process_event(%s, %v)", eventType, args)
 
-	// Restart watchdog
-	if y.watchdogTask != nil {
-		y.watchdogTask()
-	}
-
 	switch eventType {
 	case "onStateChange":
-		if data, ok := args[0].(map[string]interface{}); ok {
-			if y.muteAds && data["state"] == "1" {
+		if data, ok := eventData(args); ok {
+			if y.muteAds.Load() && data["state"] == "1" {
 				go y.Mute(false, true)
 			}
 		}
 
 	case "nowPlaying":
-		if data, ok := args[0].(map[string]interface{}); ok {
-			if y.muteAds && data["state"] == "1" {
+		if data, ok := eventData(args); ok {
+			if y.muteAds.Load() && data["state"] == "1" {
 				y.logger.Info("Ad has ended, unmuting")
 				go y.Mute(false, true)
 			}
 		}
 
 	case "onAdStateChange":
-		if data, ok := args[0].(map[string]interface{}); ok {
+		if data, ok := eventData(args); ok {
 			if data["adState"] == "0" {
 				y.logger.Info("Ad has ended, unmuting")
 				go y.Mute(false, true)
-			} else if y.skipAds && data["isSkipEnabled"] == "true" {
+			} else if y.skipAds.Load() && data["isSkipEnabled"] == "true" {
 				y.logger.Info("Ad can be skipped, skipping")
 				go y.SkipAd()
 				go y.Mute(false, true)
-			} else if y.muteAds {
+			} else if y.muteAds.Load() {
 				y.logger.Info("Ad has started, muting")
 				go y.Mute(true, true)
 			}
 		}
 
 	case "onVolumeChanged":
-		if len(args) > 0 {
-			y.volumeState = args[0].(map[string]interface{})
+		if data, ok := eventData(args); ok {
+			y.volumeState = data
 		}
 
 	case "autoplayUpNext":
-		if len(args) > 0 {
-			if data, ok := args[0].(map[string]interface{}); ok {
-				if videoID, ok := data["videoId"].(string); ok && videoID != "" {
-					y.logger.Infof("Getting segments for next video: %s", videoID)
-					go y.apiHelper.GetSegments(context.Background(), videoID)
-				}
+		if data, ok := eventData(args); ok {
+			if videoID, ok := data["videoId"].(string); ok && videoID != "" {
+				y.logger.Infof("Getting segments for next video: %s", videoID)
+				go y.apiHelper.GetSegments(context.Background(), videoID)
 			}
 		}
 
 	case "adPlaying":
-		if data, ok := args[0].(map[string]interface{}); ok {
+		if data, ok := eventData(args); ok {
 			if videoID, ok := data["contentVideoId"].(string); ok && videoID != "" {
 				y.logger.Infof("Getting segments for next video: %s", videoID)
 				go y.apiHelper.GetSegments(context.Background(), videoID)
 			}
-			if y.skipAds && data["isSkipEnabled"] == "true" {
+			if y.skipAds.Load() && data["isSkipEnabled"] == "true" {
 				y.logger.Info("Ad can be skipped, skipping")
 				go y.SkipAd()
 				go y.Mute(false, true)
-			} else if y.muteAds {
+			} else if y.muteAds.Load() {
 				y.logger.Info("Ad has started, muting")
 				go y.Mute(true, true)
 			}
 		}
 
 	case "loungeStatus":
-		if data, ok := args[0].(map[string]interface{}); ok {
+		if data, ok := eventData(args); ok {
 			if devices, ok := data["devices"].(string); ok {
 				var devicesData []map[string]interface{}
 				if err := json.Unmarshal([]byte(devices), &devicesData); err == nil {
@@ -230,7 +222,7 @@ func (y *YtLoungeApi) ProcessEvent(eventType string, args []interface{}) {
 
 	case "onSubtitlesTrackChanged":
 		if y.shortsDisconnected {
-			if data, ok := args[0].(map[string]interface{}); ok {
+			if data, ok := eventData(args); ok {
 				if videoID, ok := data["videoId"].(string); ok {
 					y.shortsDisconnected = false
 					go y.PlayVideo(videoID)
@@ -239,19 +231,17 @@ func (y *YtLoungeApi) ProcessEvent(eventType string, args []interface{}) {
 		}
 
 	case "loungeScreenDisconnected":
-		if len(args) > 0 {
-			if data, ok := args[0].(map[string]interface{}); ok {
-				if data["reason"] == "disconnectedByUserScreenInitiated" {
-					y.shortsDisconnected = true
-				}
+		if data, ok := eventData(args); ok {
+			if data["reason"] == "disconnectedByUserScreenInitiated" {
+				y.shortsDisconnected = true
 			}
 		}
 
 	case "onAutoplayModeChanged":
-		go y.SetAutoPlayMode(y.autoPlay)
+		go y.SetAutoPlayMode(y.autoPlay.Load())
 
 	case "onPlaybackSpeedChanged":
-		if data, ok := args[0].(map[string]interface{}); ok {
+		if data, ok := eventData(args); ok {
 			if speed, ok := data["playbackSpeed"].(string); ok {
 				if parsedSpeed, err := strconv.ParseFloat(speed, 64); err == nil {
 					y.playbackSpeed = parsedSpeed
@@ -271,9 +261,8 @@ func (y *YtLoungeApi) SetVolume(volume int) error {
 	y.commandMutex.Lock()
 	defer y.commandMutex.Unlock()
 
-	return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-		"command": "setVolume",
-		"volume":  volume,
+	return y.client.SendCommand(context.Background(), "setVolume", map[string]string{
+		"volume": strconv.Itoa(volume),
 	})
 }
 
@@ -290,14 +279,13 @@ func (y *YtLoungeApi) Mute(mute bool, override bool) error {
 	if override || y.volumeState["muted"] != muteStr {
 		y.volumeState["muted"] = muteStr
 		volume := 100
-		if vol, ok := y.volumeState["volume"].(float64); ok {
+		if vol, ok := EventFloat(y.volumeState["volume"]); ok {
 			volume = int(vol)
 		}
 
-		return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-			"command": "setVolume",
-			"volume":  volume,
-			"muted":   muteStr,
+		return y.client.SendCommand(context.Background(), "setVolume", map[string]string{
+			"volume": strconv.Itoa(volume),
+			"muted":  muteStr,
 		})
 	}
 
@@ -309,20 +297,27 @@ func (y *YtLoungeApi) PlayVideo(videoID string) error {
 	y.commandMutex.Lock()
 	defer y.commandMutex.Unlock()
 
-	return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-		"command": "setPlaylist",
+	return y.client.SendCommand(context.Background(), "setPlaylist", map[string]string{
 		"videoId": videoID,
 	})
 }
 
+// SeekTo seeks the current video to a position in seconds
+func (y *YtLoungeApi) SeekTo(position float64) error {
+	y.commandMutex.Lock()
+	defer y.commandMutex.Unlock()
+
+	return y.client.SendCommand(context.Background(), "seekTo", map[string]string{
+		"newTime": strconv.FormatFloat(position, 'f', 3, 64),
+	})
+}
+
 // GetNowPlaying gets the currently playing video information
 func (y *YtLoungeApi) GetNowPlaying() error {
 	y.commandMutex.Lock()
 	defer y.commandMutex.Unlock()
 
-	return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-		"command": "getNowPlaying",
-	})
+	return y.client.SendCommand(context.Background(), "getNowPlaying", nil)
 }
 
 // SkipAd skips the current advertisement if possible
@@ -330,9 +325,7 @@ func (y *YtLoungeApi) SkipAd() error {
 	y.commandMutex.Lock()
 	defer y.commandMutex.Unlock()
 
-	return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-		"command": "skipAd",
-	})
+	return y.client.SendCommand(context.Background(), "skipAd", nil)
 }
 
 // SetAutoPlayMode sets the autoplay mode
@@ -340,9 +333,12 @@ func (y *YtLoungeApi) SetAutoPlayMode(enabled bool) error {
 	y.commandMutex.Lock()
 	defer y.commandMutex.Unlock()
 
-	return y.client.SendCommand(context.Background(), y.client.ScreenID, map[string]interface{}{
-		"command":      "setAutoplayMode",
-		"autoplayMode": enabled,
+	mode := "DISABLED"
+	if enabled {
+		mode = "ENABLED"
+	}
+	return y.client.SendCommand(context.Background(), "setAutoplayMode", map[string]string{
+		"autoplayMode": mode,
 	})
 }
 
@@ -362,7 +358,7 @@ func (y *YtLoungeApi) handleEvent(event string, args []interface{}) {
 					y.logger.Infof("Getting segments for video: %s", videoID)
 					go y.apiHelper.GetSegments(context.Background(), videoID)
 				}
-				if y.muteAds && data["state"] == "1" {
+				if y.muteAds.Load() && data["state"] == "1" {
 					y.logger.Info("Ad has ended, unmuting")
 					go y.Mute(false, true)
 				}
@@ -376,7 +372,7 @@ func (y *YtLoungeApi) handleEvent(event string, args []interface{}) {
 					if state == "3" && y.shortsDisconnected {
 						y.shortsDisconnected = false
 						go y.GetNowPlaying()
-					} else if state == "1" && y.muteAds {
+					} else if state == "1" && y.muteAds.Load() {
 						go y.Mute(true, true)
 					}
 				}
@@ -390,13 +386,13 @@ func (y *YtLoungeApi) handleEvent(event string, args []interface{}) {
 					if adState == "0" {
 						y.logger.Info("Ad has ended, unmuting")
 						go y.Mute(false, true)
-					} else if y.skipAds {
+					} else if y.skipAds.Load() {
 						if isSkipEnabled, ok := data["isSkipEnabled"].(string); ok && isSkipEnabled == "true" {
 							y.logger.Info("Ad can be skipped, skipping")
 							go y.SkipAd()
 							go y.Mute(false, true)
 						}
-					} else if y.muteAds {
+					} else if y.muteAds.Load() {
 						y.logger.Info("Ad has started, muting")
 						go y.Mute(true, true)
 					}
@@ -418,7 +414,7 @@ func (y *YtLoungeApi) handleEvent(event string, args []interface{}) {
 		if len(args) > 0 {
 			if data, ok := args[0].(map[string]interface{}); ok {
 				if enabled, ok := data["autoplayMode"].(string); ok {
-					y.autoPlay = enabled == "true"
+					y.autoPlay.Store(enabled == "true")
 				}
 			}
 		}
@@ -0,0 +1,37 @@
+package ytlounge
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestProcessEventWithoutArguments(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	y := NewYtLoungeApi(nil, nil, logger)
+
+	for _, eventType := range []string{
+		"onStateChange",
+		"nowPlaying",
+		"onAdStateChange",
+		"onVolumeChanged",
+		"autoplayUpNext",
+		"adPlaying",
+		"loungeStatus",
+		"onSubtitlesTrackChanged",
+		"loungeScreenDisconnected",
+		"onPlaybackSpeedChanged",
+	} {
+		for _, args := range [][]interface{}{nil, {"not an object"}} {
+			t.Run(eventType, func(t *testing.T) {
+				y.shortsDisconnected = true
+				y.ProcessEvent(eventType, args)
+			})
+		}
+	}
+	if y.volumeState == nil {
+		t.Error("volume state was cleared by an event without data")
+	}
+}